@@ -1,13 +1,45 @@
+// One refresh at a time: concurrent requests that all hit token_expired
+// wait for the same POST /auth/refresh instead of rotating the token twice.
+let refreshing: Promise<boolean> | null = null
+
 export const useApi = () => {
   const config = useRuntimeConfig()
   const base = config.public.apiBase as string
 
+  const refresh = (): Promise<boolean> => {
+    if (!refreshing) {
+      refreshing = $fetch(`${base}/v1/auth/refresh`, {
+        method: 'POST',
+        credentials: 'include',
+      })
+        .then(() => true)
+        .catch(() => false)
+        .finally(() => {
+          refreshing = null
+        })
+    }
+    return refreshing
+  }
+
+  // Access tokens are short-lived: on token_expired, refresh once and retry once.
+  const request = async <T>(path: string, opts: Record<string, any> = {}): Promise<T> => {
+    const call = () => $fetch<T>(`${base}${path}`, { ...opts, credentials: 'include' })
+    try {
+      return await call()
+    } catch (e: any) {
+      if (e?.status !== 401 || e?.data?.error !== 'token_expired' || !(await refresh())) {
+        throw e
+      }
+      return await call()
+    }
+  }
+
   const get = async <T>(path: string): Promise<T> => {
-    return await $fetch<T>(`${base}${path}`)
+    return await request<T>(path)
   }
 
-  const post = async <T>(path: string, body: any): Promise<T> => {
-    return await $fetch<T>(`${base}${path}`, {
+  const post = async <T>(path: string, body?: any): Promise<T> => {
+    return await request<T>(path, {
       method: 'POST',
       body,
     })
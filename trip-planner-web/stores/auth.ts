@@ -16,11 +16,7 @@ export const useAuthStore = defineStore('auth', {
   actions: {
     async fetchMe() {
       try {
-        const cfg = useRuntimeConfig()
-        const data = await $fetch<User>(`${cfg.public.apiBase}/v1/auth/me`, {
-          credentials: 'include'
-        })
-        this.user = data
+        this.user = await useApi().get<User>('/v1/auth/me')
       } catch {
         this.user = null
       }
@@ -42,11 +38,7 @@ export const useAuthStore = defineStore('auth', {
     },
 
     async logout() {
-      const cfg = useRuntimeConfig()
-      await $fetch(`${cfg.public.apiBase}/v1/auth/logout`, {
-        method: 'POST',
-        credentials: 'include'
-      })
+      await useApi().post('/v1/auth/logout')
       this.user = null
     }
   }
@@ -4,16 +4,49 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"trip-planner/models"
 )
 
 type Config struct {
 	// Server
 	AppPort string
 
+	// Web origins allowed to call the API (CORS) and make cookie-authenticated requests (CSRF)
+	AllowedOrigins []string
+
+	// Proxies (nginx) whose X-Forwarded-For is trusted for client IPs
+	TrustedProxies []string
+
+	// Rate limits per route group, e.g. {"auth": 10/1m, "plan": 5/1m, "default": 120/1m}
+	RateLimits map[string]RateLimit
+
+	// CORS (split web/API deployments)
+	CORSAllowCredentials bool
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSMaxAgeSeconds    int
+
 	// AI
 	OpenAIKey   string
 	OpenAIModel string
 
+	// USD per 1M tokens, used for ledger cost estimates
+	OpenAIInputCostPer1M  float64
+	OpenAIOutputCostPer1M float64
+
+	// Prompt context: places kept per stop, and the model window / output
+	// reserve the prompt is trimmed to fit
+	PromptPlaceLimit    int
+	OpenAIContextTokens int
+	OpenAIOutputReserve int
+
+	// Prompt templates: directory of <name>.tmpl files (empty = built-in), and which one to use
+	PromptTemplatesDir string
+	PromptTemplate     string
+
 	// Google Places
 	GoogleMapsKey string
 
@@ -25,46 +58,284 @@ type Config struct {
 	DBPath    string
 
 	// Cache
-	PlacesCacheHours  int
-	WeatherCacheHours int
+	PlacesCacheHours       int
+	PlaceDetailsCacheHours int
+	WeatherCacheHours      int
+
+	// Place details fetched per stop (hours, price, photos) for the prompt and items
+	PlaceDetailsLimit int
 
 	// Auth
 	GoogleClientID string
 	JWTSecret      string
 
-	CookieDomain   string
+	// JWT keyring. Empty JWTKeys => single HS256 key "default" from JWT_SECRET.
+	JWTKeys      []JWTKey
+	JWTActiveKID string
+
+	CookieDomain string
+
+	AccessTokenTTLMinutes int
+	RefreshTokenTTLDays   int
+	// A rotated refresh token stays valid this long, so tabs refreshing
+	// at the same moment aren't treated as token theft
+	RefreshReuseGraceSeconds int
+
+	// Generic OpenID Connect providers (Apple, Microsoft, company IdP, ...)
+	OIDCProviders []OIDCProvider
+
+	// Email magic-link login
+	MagicLinkURL        string // frontend page that POSTs the token to /auth/email/verify
+	MagicLinkTTLMinutes int
+	// One link per address per cooldown, whatever IP asks
+	MagicLinkCooldownSeconds int
+
+	// Email delivery
+	EmailSender    string // smtp|file
+	EmailFrom      string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUser       string
+	SMTPPass       string
+	EmailOutboxDir string
+
+	// Admin
+	AdminEmails []string
+
+	// Privacy: days between DELETE /account and the purge
+	AccountDeletionGraceDays int
 
 	// Limits
 	FreeLimit int
+	ProLimit  int
+
+	// Billing
+	BillingProvider     string // stripe|fake
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripePriceID       string
+	BillingSuccessURL   string
+	BillingCancelURL    string
+
+	// Credit packs (prepaid generations)
+	CreditPacks      []CreditPack
+	CreditExpiryDays int
+
+	// Destination countries the planner serves
+	Countries      []models.Country
+	DefaultCountry string
+
+	// Itinerary languages (ISO 639-1)
+	SupportedLanguages []string
+	DefaultLanguage    string
+}
+
+// RateLimit is one RATE_LIMITS entry: "name=requests/period[:burst]", period like 1m or 10s.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// JWTKey is one JWT_KEYS entry: "kid|alg|material[|not_after]".
+// Material is the secret for HS256 or a PEM file path for RS256/EdDSA.
+// NotAfter (RFC3339) stops a retired key verifying.
+type JWTKey struct {
+	ID       string
+	Alg      string
+	Material string
+	NotAfter time.Time
+}
+
+type OIDCProvider struct {
+	Name     string
+	Issuer   string
+	ClientID string
+}
+
+type CreditPack struct {
+	ID      string `json:"id"`
+	Credits int    `json:"credits"`
+	PriceID string `json:"-"`
+}
+
+// Pack looks up a credit pack by id.
+func (c Config) Pack(id string) (CreditPack, bool) {
+	for _, p := range c.CreditPacks {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return CreditPack{}, false
+}
+
+// Country looks up a supported destination country by ISO code.
+func (c Config) Country(code string) (models.Country, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, ct := range c.Countries {
+		if ct.Code == code {
+			return ct, true
+		}
+	}
+	return models.Country{}, false
+}
+
+// SupportsLanguage reports whether code is in SUPPORTED_LANGUAGES.
+func (c Config) SupportsLanguage(code string) bool {
+	for _, l := range c.SupportedLanguages {
+		if l == code {
+			return true
+		}
+	}
+	return false
 }
 
 func Load() Config {
-	return Config{
+	cfg := Config{
 		AppPort: getEnv("APP_PORT", "8080"),
 
+		AllowedOrigins: getEnvListDefault("ALLOWED_ORIGINS", "http://localhost:3000"),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		RateLimits: parseRateLimits(getEnv("RATE_LIMITS", "default=120/1m,auth=10/1m,plan=5/1m")),
+
+		CORSAllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+		CORSAllowedMethods:   getEnvListDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,OPTIONS"),
+		CORSAllowedHeaders:   getEnvListDefault("CORS_ALLOWED_HEADERS", "Content-Type,Authorization"),
+		CORSMaxAgeSeconds:    getEnvInt("CORS_MAX_AGE_SECONDS", 600),
+
 		OpenAIKey:   mustEnv("OPENAI_API_KEY"),
 		OpenAIModel: getEnv("OPENAI_MODEL", "gpt-5.2"),
 
+		OpenAIInputCostPer1M:  getEnvFloat("OPENAI_INPUT_COST_PER_1M", 1.75),
+		OpenAIOutputCostPer1M: getEnvFloat("OPENAI_OUTPUT_COST_PER_1M", 14.0),
+
+		PromptPlaceLimit:    getEnvInt("PROMPT_PLACE_LIMIT", 12),
+		OpenAIContextTokens: getEnvInt("OPENAI_CONTEXT_TOKENS", 128000),
+		OpenAIOutputReserve: getEnvInt("OPENAI_OUTPUT_RESERVE_TOKENS", 16000),
+
+		PromptTemplatesDir: getEnv("PROMPT_TEMPLATES_DIR", ""),
+		PromptTemplate:     getEnv("PROMPT_TEMPLATE", "itinerary"),
+
 		GoogleMapsKey:  mustEnv("GOOGLE_MAPS_API_KEY"),
 		OpenWeatherKey: getEnv("OPENWEATHER_API_KEY", ""),
 
 		PlansFile: getEnv("PLANS_FILE", "/app/storage/plans.json"),
 		DBPath:    getEnv("DB_PATH", "/app/storage/app.db"),
 
-		PlacesCacheHours:  getEnvInt("PLACES_CACHE_HOURS", 168),
-		WeatherCacheHours: getEnvInt("WEATHER_CACHE_HOURS", 2),
+		PlacesCacheHours:       getEnvInt("PLACES_CACHE_HOURS", 168),
+		PlaceDetailsCacheHours: getEnvInt("PLACE_DETAILS_CACHE_HOURS", 72),
+		WeatherCacheHours:      getEnvInt("WEATHER_CACHE_HOURS", 2),
+
+		PlaceDetailsLimit: getEnvInt("PLACE_DETAILS_LIMIT", 6),
 
 		GoogleClientID: mustEnv("GOOGLE_CLIENT_ID"),
-		JWTSecret:      mustEnv("JWT_SECRET"),
+		JWTSecret:      getEnv("JWT_SECRET", ""),
+		JWTKeys:        parseJWTKeys(getEnv("JWT_KEYS", "")),
+		JWTActiveKID:   getEnv("JWT_ACTIVE_KID", "default"),
 
 		CookieDomain: getEnv("COOKIE_DOMAIN", "localhost"),
 
+		AccessTokenTTLMinutes: getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLDays:   getEnvInt("REFRESH_TOKEN_TTL_DAYS", 30),
+
+		RefreshReuseGraceSeconds: getEnvInt("REFRESH_REUSE_GRACE_SECONDS", 30),
+
+		OIDCProviders: parseOIDCProviders(getEnv("OIDC_PROVIDERS", "")),
+
+		MagicLinkURL:        getEnv("MAGIC_LINK_URL", "http://localhost:3000/auth/verify"),
+		MagicLinkTTLMinutes: getEnvInt("MAGIC_LINK_TTL_MINUTES", 15),
+
+		MagicLinkCooldownSeconds: getEnvInt("MAGIC_LINK_COOLDOWN_SECONDS", 60),
+
+		EmailSender:    getEnv("EMAIL_SENDER", "file"),
+		EmailFrom:      getEnv("EMAIL_FROM", "Travel Planner <no-reply@localhost>"),
+		SMTPHost:       getEnv("SMTP_HOST", ""),
+		SMTPPort:       getEnv("SMTP_PORT", "587"),
+		SMTPUser:       getEnv("SMTP_USER", ""),
+		SMTPPass:       getEnv("SMTP_PASS", ""),
+		EmailOutboxDir: getEnv("EMAIL_OUTBOX_DIR", "/app/storage/outbox"),
+
+		AdminEmails: getEnvList("ADMIN_EMAILS"),
+
+		AccountDeletionGraceDays: getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 14),
+
 		FreeLimit: getEnvInt("FREE_LIMIT", 2),
+		ProLimit:  getEnvInt("PRO_LIMIT", 100),
+
+		BillingProvider:     getEnv("BILLING_PROVIDER", "fake"),
+		StripeSecretKey:     getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripePriceID:       getEnv("STRIPE_PRICE_ID", ""),
+		BillingSuccessURL:   getEnv("BILLING_SUCCESS_URL", "http://localhost:3000/billing/success"),
+		BillingCancelURL:    getEnv("BILLING_CANCEL_URL", "http://localhost:3000/billing/cancel"),
+
+		CreditPacks:      parseCreditPacks(getEnv("CREDIT_PACKS", "single:1:,trip:3:,explorer:10:")),
+		CreditExpiryDays: getEnvInt("CREDIT_EXPIRY_DAYS", 365),
+
+		Countries:      parseCountries(getEnv("COUNTRIES", defaultCountries)),
+		DefaultCountry: strings.ToUpper(getEnv("DEFAULT_COUNTRY", "LK")),
+
+		SupportedLanguages: getEnvListDefault("SUPPORTED_LANGUAGES", "en,si,ta,de,fr,es,it,nl,ru,zh,ja,hi"),
+		DefaultLanguage:    getEnv("DEFAULT_LANGUAGE", "en"),
 	}
+	cfg.validate()
+	return cfg
 }
 
+// validate stops startup on settings that would be unsafe at runtime.
+func (c Config) validate() {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" && c.CORSAllowCredentials {
+			log.Fatalf("ALLOWED_ORIGINS=* with CORS_ALLOW_CREDENTIALS=true would let any site read logged-in responses; list origins or disable credentials")
+		}
+	}
+	if c.BillingProvider == "stripe" {
+		for _, p := range c.CreditPacks {
+			if p.PriceID == "" {
+				log.Fatalf("CREDIT_PACKS: pack %q has no Stripe price id (want id:credits:price_id)", p.ID)
+			}
+		}
+	}
+}
+
+const defaultCountries = "LK|Sri Lanka|LKR|Asia/Colombo|en-LK," +
+	"IN|India|INR|Asia/Kolkata|en-IN," +
+	"MV|Maldives|MVR|Indian/Maldives|en-MV," +
+	"TH|Thailand|THB|Asia/Bangkok|th-TH," +
+	"JP|Japan|JPY|Asia/Tokyo|ja-JP," +
+	"GB|United Kingdom|GBP|Europe/London|en-GB," +
+	"DE|Germany|EUR|Europe/Berlin|de-DE"
+
 // ---------- helpers ----------
 
+// parseCountries reads "CODE|Name|CUR|Timezone|locale" entries separated by commas.
+func parseCountries(v string) []models.Country {
+	out := []models.Country{}
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "|")
+		if len(parts) != 5 || len(parts[0]) != 2 || parts[1] == "" || len(parts[2]) != 3 {
+			if strings.TrimSpace(entry) != "" {
+				log.Printf("ignoring invalid country %q", entry)
+			}
+			continue
+		}
+		if _, err := time.LoadLocation(parts[3]); err != nil {
+			log.Printf("ignoring country %q: %v", entry, err)
+			continue
+		}
+		out = append(out, models.Country{
+			Code:     strings.ToUpper(parts[0]),
+			Name:     parts[1],
+			Currency: strings.ToUpper(parts[2]),
+			Timezone: parts[3],
+			Locale:   parts[4],
+		})
+	}
+	return out
+}
+
 func mustEnv(key string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -92,3 +363,136 @@ func getEnvInt(key string, def int) int {
 	}
 	return i
 }
+
+// getEnvList splits a comma-separated env var, dropping empty entries.
+func getEnvList(key string) []string {
+	return getEnvListDefault(key, "")
+}
+
+func getEnvListDefault(key, def string) []string {
+	out := []string{}
+	for _, v := range strings.Split(getEnv(key, def), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func parseRateLimits(v string) map[string]RateLimit {
+	out := map[string]RateLimit{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		reqs, rest, ok2 := strings.Cut(spec, "/")
+		if !ok || !ok2 {
+			log.Printf("ignoring invalid rate limit %q", entry)
+			continue
+		}
+		period, burst, _ := strings.Cut(rest, ":")
+		l := RateLimit{}
+		var err error
+		if l.Requests, err = strconv.Atoi(reqs); err != nil {
+			log.Printf("ignoring invalid rate limit %q", entry)
+			continue
+		}
+		if l.Period, err = time.ParseDuration(period); err != nil || l.Period <= 0 {
+			log.Printf("ignoring invalid rate limit %q", entry)
+			continue
+		}
+		if burst != "" {
+			l.Burst, _ = strconv.Atoi(burst)
+		}
+		out[strings.TrimSpace(name)] = l
+	}
+	return out
+}
+
+func parseJWTKeys(v string) []JWTKey {
+	out := []JWTKey{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		if len(parts) < 3 || len(parts) > 4 {
+			log.Fatalf("invalid JWT_KEYS entry for key %q", parts[0])
+		}
+		k := JWTKey{ID: parts[0], Alg: parts[1], Material: parts[2]}
+		if len(parts) == 4 {
+			t, err := time.Parse(time.RFC3339, parts[3])
+			if err != nil {
+				log.Fatalf("invalid not_after for JWT key %q: %v", k.ID, err)
+			}
+			k.NotAfter = t
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// parseOIDCProviders reads "name|issuer|client_id" entries separated by commas.
+func parseOIDCProviders(v string) []OIDCProvider {
+	out := []OIDCProvider{}
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "|")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			if strings.TrimSpace(entry) != "" {
+				log.Printf("ignoring invalid OIDC provider %q", entry)
+			}
+			continue
+		}
+		out = append(out, OIDCProvider{Name: parts[0], Issuer: parts[1], ClientID: parts[2]})
+	}
+	return out
+}
+
+// parseCreditPacks reads "id:credits:stripe_price_id" entries separated by commas.
+func parseCreditPacks(v string) []CreditPack {
+	out := []CreditPack{}
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) < 2 || parts[0] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			log.Printf("ignoring invalid credit pack %q", entry)
+			continue
+		}
+		p := CreditPack{ID: parts[0], Credits: n}
+		if len(parts) > 2 {
+			p.PriceID = parts[2]
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func getEnvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/config"
+	"trip-planner/models"
+	"trip-planner/storage"
+	"trip-planner/utils"
+)
+
+type AccountController struct {
+	cfg config.Config
+	db  *sql.DB
+
+	store *utils.JSONStore[models.TripPlan]
+}
+
+func NewAccountController(cfg config.Config, db *sql.DB) *AccountController {
+	return &AccountController{
+		cfg:   cfg,
+		db:    db,
+		store: utils.NewJSONStore[models.TripPlan](cfg.PlansFile),
+	}
+}
+
+// GET /api/v1/account/export?format=zip|json
+// Everything we store about the logged user. ZIP (one JSON file per section) by default.
+func (a *AccountController) Export(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	data, err := a.collect(uid)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "export_failed", "details": err.Error()})
+		return
+	}
+
+	stamp := time.Now().UTC().Format("20060102-150405")
+
+	if c.Query("format") == "json" {
+		c.Header("Content-Disposition", `attachment; filename="trip-planner-export-`+stamp+`.json"`)
+		c.JSON(http.StatusOK, data)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="trip-planner-export-`+stamp+`.zip"`)
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	for name, section := range data {
+		w, err := zw.Create(name + ".json")
+		if err != nil {
+			return
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(section); err != nil {
+			return
+		}
+	}
+	_ = zw.Close()
+}
+
+// DELETE /api/v1/account
+// Schedules deletion after ACCOUNT_DELETION_GRACE_DAYS and signs out everywhere.
+// Logging in again before delete_after cancels it.
+func (a *AccountController) Delete(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deleteAfter := time.Now().AddDate(0, 0, a.cfg.AccountDeletionGraceDays)
+	if err := storage.RequestAccountDeletion(a.db, uid, deleteAfter); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+
+	_, _ = storage.RevokeUserSessions(a.db, uid, "")
+	_ = storage.RevokeUserAPIKeys(a.db, uid)
+	clearAuthCookies(c, a.cfg.CookieDomain)
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok":           true,
+		"delete_after": deleteAfter.Unix(),
+	})
+}
+
+// GET /api/v1/account/preferences
+func (a *AccountController) GetPreferences(c *gin.Context) {
+	prefs, err := storage.GetPreferences(a.db, c.GetString("uid"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// PUT /api/v1/account/preferences
+// Replaces the saved preferences; used as defaults by CreatePlan / Regenerate
+func (a *AccountController) PutPreferences(c *gin.Context) {
+	var prefs models.UserPreferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": err.Error()})
+		return
+	}
+	if prefs.Language != "" && !a.cfg.SupportsLanguage(prefs.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_language", "details": prefs.Language})
+		return
+	}
+	if err := storage.SavePreferences(a.db, c.GetString("uid"), prefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (a *AccountController) collect(uid string) (map[string]any, error) {
+	user, err := storage.GetUser(a.db, uid)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := storage.ListIdentities(a.db, uid)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := storage.ListActiveSessions(a.db, uid)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys, err := storage.ListAPIKeys(a.db, uid)
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := storage.ListLedger(a.db, uid, 100000)
+	if err != nil {
+		return nil, err
+	}
+	purchases, err := storage.ListCreditPurchases(a.db, uid)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := storage.GetPreferences(a.db, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := a.store.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	plans := make([]models.TripPlan, 0)
+	for _, p := range all {
+		if p.UserID == uid {
+			plans = append(plans, p)
+		}
+	}
+
+	return map[string]any{
+		"user":             user,
+		"identities":       identities,
+		"sessions":         sessions,
+		"api_keys":         apiKeys,
+		"usage_ledger":     ledger,
+		"credit_purchases": purchases,
+		"preferences":      prefs,
+		"plans":            plans,
+	}, nil
+}
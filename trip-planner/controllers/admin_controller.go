@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/config"
+	"trip-planner/models"
+	"trip-planner/storage"
+	"trip-planner/utils"
+)
+
+type AdminController struct {
+	cfg config.Config
+	db  *sql.DB
+
+	store *utils.JSONStore[models.TripPlan]
+}
+
+func NewAdminController(cfg config.Config, db *sql.DB) *AdminController {
+	return &AdminController{
+		cfg:   cfg,
+		db:    db,
+		store: utils.NewJSONStore[models.TripPlan](cfg.PlansFile),
+	}
+}
+
+// GET /api/v1/admin/users?q=&limit=&offset=
+func (a *AdminController) ListUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	users, err := storage.SearchUsers(a.db, c.Query("q"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// GET /api/v1/admin/users/:id
+func (a *AdminController) GetUser(c *gin.Context) {
+	u, ok := a.loadUser(c)
+	if !ok {
+		return
+	}
+	summary, err := storage.SummarizeLedger(a.db, u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"user":  u,
+		"limit": a.limitFor(u),
+		"usage": summary,
+	})
+}
+
+// GET /api/v1/admin/users/:id/plans
+func (a *AdminController) UserPlans(c *gin.Context) {
+	u, ok := a.loadUser(c)
+	if !ok {
+		return
+	}
+	all, err := a.store.ReadAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	out := make([]models.TripPlan, 0)
+	for _, p := range all {
+		if p.UserID == u.ID {
+			out = append(out, p)
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /api/v1/admin/users/:id/ledger?limit=
+func (a *AdminController) UserLedger(c *gin.Context) {
+	u, ok := a.loadUser(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	entries, err := storage.ListLedger(a.db, u.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// Request body: { "limit": 10 } or { "limit": null } to restore FREE_LIMIT
+type quotaReq struct {
+	Limit *int `json:"limit" binding:"omitempty,min=0"`
+}
+
+// PUT /api/v1/admin/users/:id/quota
+func (a *AdminController) SetQuota(c *gin.Context) {
+	u, ok := a.loadUser(c)
+	if !ok {
+		return
+	}
+	var req quotaReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": err.Error()})
+		return
+	}
+	if err := storage.SetQuotaOverride(a.db, u.ID, req.Limit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	u.QuotaOverride = req.Limit
+	c.JSON(http.StatusOK, gin.H{"ok": true, "limit": a.limitFor(u)})
+}
+
+// POST /api/v1/admin/users/:id/ban
+// Also signs the user out everywhere and revokes their API keys (unban doesn't restore them).
+func (a *AdminController) Ban(c *gin.Context) {
+	a.setBanned(c, true)
+}
+
+// POST /api/v1/admin/users/:id/unban
+func (a *AdminController) Unban(c *gin.Context) {
+	a.setBanned(c, false)
+}
+
+// POST /api/v1/admin/users/:id/reset-usage
+func (a *AdminController) ResetUsage(c *gin.Context) {
+	u, ok := a.loadUser(c)
+	if !ok {
+		return
+	}
+	if err := storage.ResetUsage(a.db, u.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+func (a *AdminController) setBanned(c *gin.Context, banned bool) {
+	u, ok := a.loadUser(c)
+	if !ok {
+		return
+	}
+	if err := storage.SetBanned(a.db, u.ID, banned); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	if banned {
+		_, _ = storage.RevokeUserSessions(a.db, u.ID, "")
+		if err := storage.RevokeUserAPIKeys(a.db, u.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "banned": banned})
+}
+
+// loadUser reads :id and writes a 404 when the user does not exist.
+func (a *AdminController) loadUser(c *gin.Context) (storage.User, bool) {
+	u, err := storage.GetUser(a.db, c.Param("id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return u, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return u, false
+	}
+	return u, true
+}
+
+func (a *AdminController) limitFor(u storage.User) int {
+	return generationLimit(a.cfg, storage.Usage{
+		Generations:   u.Generations,
+		QuotaOverride: u.QuotaOverride,
+		Tier:          u.Tier,
+	})
+}
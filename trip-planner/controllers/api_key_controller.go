@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"trip-planner/storage"
+)
+
+type APIKeyController struct {
+	db *sql.DB
+}
+
+func NewAPIKeyController(db *sql.DB) *APIKeyController {
+	return &APIKeyController{db: db}
+}
+
+// GET /api/v1/auth/api-keys
+func (k *APIKeyController) List(c *gin.Context) {
+	keys, err := storage.ListAPIKeys(k.db, c.GetString("uid"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Request body: { "name": "laptop script", "scopes": ["read","write"] }
+type createAPIKeyReq struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+}
+
+// POST /api/v1/auth/api-keys
+// The raw key is only returned here; we keep its hash.
+func (k *APIKeyController) Create(c *gin.Context) {
+	// keys can't mint more keys
+	if c.GetString("auth_method") != "session" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "session_required"})
+		return
+	}
+
+	var req createAPIKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": err.Error()})
+		return
+	}
+
+	// write implies read
+	scopes := []string{storage.ScopeRead}
+	for _, s := range req.Scopes {
+		if s == storage.ScopeWrite {
+			scopes = append(scopes, storage.ScopeWrite)
+			break
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_failed"})
+		return
+	}
+	raw := storage.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	key := storage.APIKey{
+		ID:        uuid.NewString(),
+		UserID:    c.GetString("uid"),
+		Name:      req.Name,
+		Prefix:    raw[:len(storage.APIKeyPrefix)+6],
+		Scopes:    scopes,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := storage.InsertAPIKey(k.db, key, raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"key": raw, "api_key": key})
+}
+
+// DELETE /api/v1/auth/api-keys/:id
+func (k *APIKeyController) Revoke(c *gin.Context) {
+	err := storage.RevokeAPIKey(k.db, c.Param("id"), c.GetString("uid"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
@@ -1,29 +1,44 @@
 package controllers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
+	"trip-planner/config"
 	"trip-planner/services"
+	"trip-planner/storage"
 	"trip-planner/utils"
 )
 
 type AuthController struct {
-	db        *sql.DB
-	auth      *services.AuthService
-	jwtSecret string
-	domain    string // cookie domain (e.g. travel.geekmacsolutions.com)
+	cfg    config.Config
+	db     *sql.DB
+	auth   *services.AuthService
+	mailer services.EmailSender
+	keys   *utils.Keyring
+	domain string // cookie domain (e.g. travel.geekmacsolutions.com)
 }
 
-func NewAuthController(db *sql.DB, auth *services.AuthService, jwtSecret string, cookieDomain string) *AuthController {
+func NewAuthController(cfg config.Config, db *sql.DB, auth *services.AuthService, mailer services.EmailSender, keys *utils.Keyring) *AuthController {
 	return &AuthController{
-		db:        db,
-		auth:      auth,
-		jwtSecret: jwtSecret,
-		domain:    cookieDomain,
+		cfg:    cfg,
+		db:     db,
+		auth:   auth,
+		mailer: mailer,
+		keys:   keys,
+		domain: cfg.CookieDomain,
 	}
 }
 
@@ -51,47 +66,197 @@ func (a *AuthController) GoogleLogin(c *gin.Context) {
 		return
 	}
 
-	now := time.Now().Unix()
+	// existing Google users keep their sub as user id
+	email := verifiedEmail(u.Email, u.EmailVerified)
+	uid, ok := a.resolveUser(c, "google", u.Sub, email, u.Sub)
+	if !ok {
+		return
+	}
 
-	// upsert user (SQLite style shown; adapt if using other DB)
-	_, _ = a.db.Exec(`INSERT OR IGNORE INTO users (id,email,name,picture,created_at) VALUES (?,?,?,?,?)`,
-		u.Sub, u.Email, u.Name, u.Picture, now)
-	_, _ = a.db.Exec(`UPDATE users SET email=?, name=?, picture=? WHERE id=?`,
-		u.Email, u.Name, u.Picture, u.Sub)
+	if banned, _ := storage.IsBanned(a.db, uid); banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+		return
+	}
 
-	// ensure usage row
-	_, _ = a.db.Exec(`INSERT OR IGNORE INTO usage (user_id,generations,updated_at) VALUES (?,?,?)`,
-		u.Sub, 0, now)
+	a.upsertUser(uid, email, u.Name, u.Picture)
+
+	if !a.startSession(c, uid) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok": true,
+		"user": gin.H{
+			"id":      uid,
+			"email":   email,
+			"name":    u.Name,
+			"picture": u.Picture,
+		},
+	})
+}
+
+// GET /api/v1/auth/providers
+func (a *AuthController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"google": a.auth.GoogleClientID != "",
+		"email":  true,
+		"oidc":   a.auth.OIDCProviders(),
+	})
+}
+
+// Request body: { "id_token": "<provider id token>", "nonce": "<optional>" }
+type oidcLoginReq struct {
+	IDToken string `json:"id_token" binding:"required"`
+	Nonce   string `json:"nonce"`
+}
+
+// POST /api/v1/auth/oidc/:provider
+// Same as GoogleLogin for any configured OIDC issuer
+func (a *AuthController) OIDCLogin(c *gin.Context) {
+	provider := c.Param("provider")
 
-	jwtToken, err := utils.SignUserJWT(a.jwtSecret, u.Sub)
+	var req oidcLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing_token"})
+		return
+	}
+
+	id, err := a.auth.VerifyOIDCToken(c.Request.Context(), provider, req.IDToken, req.Nonce)
+	if errors.Is(err, services.ErrUnknownProvider) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown_provider"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "jwt_failed"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "details": err.Error()})
 		return
 	}
 
-	// Cookie settings
-	// SameSite Lax works well for same-site requests
-	c.SetSameSite(http.SameSiteLaxMode)
+	email := verifiedEmail(id.Email, id.EmailVerified)
+	uid, ok := a.resolveUser(c, provider, id.Subject, email, provider+"|"+id.Subject)
+	if !ok {
+		return
+	}
 
-	// Set cookie domain explicitly (recommended).
-	// If a.domain == "" -> host-only cookie (still ok).
-	c.SetCookie(
-		"session",
-		jwtToken,
-		60*60*24*30, // 30 days
-		"/",
-		a.domain,
-		true, // Secure (HTTPS)
-		true, // HttpOnly
-	)
+	if banned, _ := storage.IsBanned(a.db, uid); banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+		return
+	}
+
+	a.upsertUser(uid, email, id.Name, id.Picture)
+
+	if !a.startSession(c, uid) {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"ok": true,
 		"user": gin.H{
-			"id":      u.Sub,
-			"email":   u.Email,
-			"name":    u.Name,
-			"picture": u.Picture,
+			"id":      uid,
+			"email":   email,
+			"name":    id.Name,
+			"picture": id.Picture,
+		},
+	})
+}
+
+// Request body: { "email": "traveller@example.com" }
+type emailLoginReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// POST /api/v1/auth/email/request
+// Emails a one-time login link. Always answers ok so it can't be used to probe accounts.
+func (a *AuthController) RequestEmailLogin(c *gin.Context) {
+	var req emailLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_failed"})
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	ttl := time.Duration(a.cfg.MagicLinkTTLMinutes) * time.Minute
+	cooldown := time.Duration(a.cfg.MagicLinkCooldownSeconds) * time.Second
+	issued, err := storage.InsertLoginToken(a.db, hashLoginToken(token), email, time.Now().Add(ttl), cooldown)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed"})
+		return
+	}
+	if !issued {
+		// a link was just sent; answer the same so account existence doesn't leak
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		return
+	}
+
+	link := a.cfg.MagicLinkURL + "?token=" + url.QueryEscape(token)
+	err = a.mailer.Send(c.Request.Context(), services.Email{
+		To:      email,
+		Subject: "Your Travel Planner sign-in link",
+		Text: "Click the link below to sign in. It expires in " + itoa(a.cfg.MagicLinkTTLMinutes) +
+			" minutes and can only be used once.\r\n\r\n" + link + "\r\n\r\n" +
+			"If you didn't ask for this, you can ignore this email.\r\n",
+	})
+	if err != nil {
+		log.Printf("magic link email to %s failed: %v", email, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "email_failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// Request body: { "token": "<token from the emailed link>" }
+type emailVerifyReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// POST /api/v1/auth/email/verify
+// Consumes the magic-link token and issues the same session cookie as GoogleLogin
+func (a *AuthController) VerifyEmailLogin(c *gin.Context) {
+	var req emailVerifyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing_token"})
+		return
+	}
+
+	email, err := storage.ConsumeLoginToken(a.db, hashLoginToken(req.Token))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	// the link proves the email, so an existing (e.g. Google) account with it is the same user
+	uid, ok := a.resolveUser(c, "email", email, email, "email|"+uuid.NewString())
+	if !ok {
+		return
+	}
+	a.upsertUser(uid, email, "", "")
+
+	if banned, _ := storage.IsBanned(a.db, uid); banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+		return
+	}
+
+	if !a.startSession(c, uid) {
+		return
+	}
+
+	var name, picture string
+	_ = a.db.QueryRow(`SELECT COALESCE(name,''),COALESCE(picture,'') FROM users WHERE id=?`, uid).Scan(&name, &picture)
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok": true,
+		"user": gin.H{
+			"id":      uid,
+			"email":   email,
+			"name":    name,
+			"picture": picture,
 		},
 	})
 }
@@ -111,16 +276,288 @@ func (a *AuthController) Me(c *gin.Context) {
 		return
 	}
 
+	credits, _ := storage.CreditBalance(a.db, uid.(string))
+
 	c.JSON(http.StatusOK, gin.H{
 		"id":      uid,
 		"email":   email,
 		"name":    name,
 		"picture": picture,
+		"credits": credits,
 	})
 }
 
 func (a *AuthController) Logout(c *gin.Context) {
+	_ = storage.RevokeSessionFamily(a.db, c.GetString("sid"))
+	a.clearCookie(c)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GET /api/v1/auth/sessions
+func (a *AuthController) ListSessions(c *gin.Context) {
+	uid := c.GetString("uid")
+	sessions, err := storage.ListActiveSessions(a.db, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+
+	current := c.GetString("sid")
+	out := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, gin.H{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DELETE /api/v1/auth/sessions/:id
+func (a *AuthController) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	err := storage.RevokeSession(a.db, id, c.GetString("uid"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	if id == c.GetString("sid") {
+		a.clearCookie(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DELETE /api/v1/auth/sessions?except_current=true
+func (a *AuthController) RevokeAllSessions(c *gin.Context) {
+	keep := ""
+	if c.Query("except_current") == "true" {
+		keep = c.GetString("sid")
+	}
+	n, err := storage.RevokeUserSessions(a.db, c.GetString("uid"), keep)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	if keep == "" {
+		a.clearCookie(c)
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "revoked": n})
+}
+
+const refreshCookiePath = "/api/v1/auth"
+
+func (a *AuthController) clearCookie(c *gin.Context) {
+	clearAuthCookies(c, a.domain)
+}
+
+func clearAuthCookies(c *gin.Context, domain string) {
 	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("session", "", -1, "/", a.domain, true, true)
+	c.SetCookie("session", "", -1, "/", domain, true, true)
+	c.SetCookie("refresh", "", -1, refreshCookiePath, domain, true, true)
+}
+
+// resolveUser maps an external identity to a user id. Known identities map
+// directly; otherwise an email that matches an existing account links to it,
+// and anything else becomes a new user with newID. email must be verified
+// (see verifiedEmail). On failure it writes the error response and returns false.
+func (a *AuthController) resolveUser(c *gin.Context, provider, subject, email string, newID string) (string, bool) {
+	uid, err := storage.FindIdentity(a.db, provider, subject)
+	if err == nil {
+		return uid, true
+	}
+	if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user_read_failed"})
+		return "", false
+	}
+
+	uid = newID
+	if email != "" {
+		existing, err := storage.GetUserIDByEmail(a.db, email)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "user_read_failed"})
+			return "", false
+		default:
+			uid = existing
+		}
+	}
+
+	if err := storage.LinkIdentity(a.db, provider, subject, uid, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed"})
+		return "", false
+	}
+	return uid, true
+}
+
+// verifiedEmail drops emails the provider has not verified. users.email
+// drives account linking, magic links and ADMIN_EMAILS, so an unverified
+// address must never be stored.
+func verifiedEmail(email string, verified bool) string {
+	if !verified {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// upsertUser creates or refreshes the users row and ensures a usage row.
+// Empty name/picture never overwrite existing values.
+func (a *AuthController) upsertUser(id, email, name, picture string) {
+	now := time.Now().Unix()
+
+	// upsert user (SQLite style shown; adapt if using other DB)
+	// empty email => NULL (email is UNIQUE) and never overwrites a stored one
+	_, _ = a.db.Exec(`INSERT OR IGNORE INTO users (id,email,name,picture,created_at) VALUES (?,NULLIF(?,''),?,?,?)`,
+		id, email, name, picture, now)
+	_, _ = a.db.Exec(`UPDATE users SET email=COALESCE(NULLIF(?,''),email), name=COALESCE(NULLIF(?,''),name), picture=COALESCE(NULLIF(?,''),picture) WHERE id=?`,
+		email, name, picture, id)
+
+	// ensure usage row
+	_, _ = a.db.Exec(`INSERT OR IGNORE INTO usage (user_id,generations,updated_at) VALUES (?,?,?)`,
+		id, 0, now)
+}
+
+// startSession creates a session (refresh token family) and sets the access
+// and refresh cookies. On failure it writes the error response and returns false.
+func (a *AuthController) startSession(c *gin.Context, uid string) bool {
+	now := time.Now()
+	sess := storage.Session{
+		ID:         uuid.NewString(),
+		UserID:     uid,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		CreatedAt:  now.Unix(),
+		LastSeenAt: now.Unix(),
+		ExpiresAt:  now.Add(a.refreshTTL()).Unix(),
+	}
+	if err := storage.InsertSession(a.db, sess); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session_failed"})
+		return false
+	}
+
+	// logging in during the deletion grace period keeps the account
+	if cancelled, _ := storage.CancelAccountDeletion(a.db, uid); cancelled {
+		log.Printf("account %s deletion cancelled by login", uid)
+	}
+
+	return a.issueTokens(c, uid, sess.ID)
+}
+
+// POST /api/v1/auth/refresh
+// Rotates the refresh token and issues a new access token. A refresh token
+// that was already used revokes its whole session (stolen token replay).
+func (a *AuthController) Refresh(c *gin.Context) {
+	raw, err := c.Cookie("refresh")
+	if err != nil || raw == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	hash := hashLoginToken(raw)
+
+	rt, err := storage.GetRefreshToken(a.db, hash)
+	if err != nil {
+		a.clearCookie(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if rt.UsedAt == 0 && storage.MarkRefreshTokenUsed(a.db, hash) != nil {
+		// lost a race with another tab; re-read when it was used
+		if rt, err = storage.GetRefreshToken(a.db, hash); err != nil || rt.UsedAt == 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "session_failed"})
+			return
+		}
+	}
+	// a token rotated moments ago is a concurrent refresh, not a replay
+	grace := time.Duration(a.cfg.RefreshReuseGraceSeconds) * time.Second
+	if rt.UsedAt != 0 && time.Since(time.Unix(rt.UsedAt, 0)) > grace {
+		_ = storage.RevokeSessionFamily(a.db, rt.SessionID)
+		a.clearCookie(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh_reused"})
+		return
+	}
+
+	if rt.ExpiresAt <= time.Now().Unix() {
+		a.clearCookie(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if _, err := storage.GetActiveSession(a.db, rt.SessionID, rt.UserID); err != nil {
+		a.clearCookie(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "session_revoked"})
+		return
+	}
+	if banned, _ := storage.IsBanned(a.db, rt.UserID); banned {
+		c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+		return
+	}
+
+	_ = storage.ExtendSession(a.db, rt.SessionID, time.Now().Add(a.refreshTTL()))
+	if !a.issueTokens(c, rt.UserID, rt.SessionID) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
+
+// issueTokens signs a new access JWT, stores a new refresh token for the
+// session and sets both cookies.
+func (a *AuthController) issueTokens(c *gin.Context, uid, sessionID string) bool {
+	accessTTL := time.Duration(a.cfg.AccessTokenTTLMinutes) * time.Minute
+	jwtToken, err := utils.SignUserJWT(a.keys, uid, sessionID, accessTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "jwt_failed"})
+		return false
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_failed"})
+		return false
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(buf)
+	if err := storage.InsertRefreshToken(a.db, hashLoginToken(refresh), sessionID, uid, time.Now().Add(a.refreshTTL())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session_failed"})
+		return false
+	}
+
+	maxAge := int(a.refreshTTL().Seconds())
+
+	// Cookie settings
+	// SameSite Lax works well for same-site requests
+	c.SetSameSite(http.SameSiteLaxMode)
+
+	// Set cookie domain explicitly (recommended).
+	// If a.domain == "" -> host-only cookie (still ok).
+	// The access cookie outlives its JWT so RequireAuth can answer
+	// "token_expired" instead of a bare "unauthorized".
+	c.SetCookie(
+		"session",
+		jwtToken,
+		maxAge,
+		"/",
+		a.domain,
+		true, // Secure (HTTPS)
+		true, // HttpOnly
+	)
+	// Refresh token is only sent to the auth routes
+	c.SetCookie("refresh", refresh, maxAge, refreshCookiePath, a.domain, true, true)
+	return true
+}
+
+func (a *AuthController) refreshTTL() time.Duration {
+	return time.Duration(a.cfg.RefreshTokenTTLDays) * 24 * time.Hour
+}
+
+func hashLoginToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
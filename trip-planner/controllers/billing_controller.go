@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/config"
+	"trip-planner/services"
+	"trip-planner/storage"
+)
+
+type BillingController struct {
+	cfg      config.Config
+	db       *sql.DB
+	provider services.BillingProvider
+}
+
+func NewBillingController(cfg config.Config, db *sql.DB, provider services.BillingProvider) *BillingController {
+	return &BillingController{cfg: cfg, db: db, provider: provider}
+}
+
+// POST /api/v1/billing/checkout
+// Returns a hosted checkout URL for the Pro upgrade
+func (b *BillingController) Checkout(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	email, err := storage.GetUserEmail(b.db, uid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sess, err := b.provider.CreateCheckoutSession(c.Request.Context(), services.CheckoutRequest{
+		UserID: uid,
+		Email:  email,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "checkout_failed", "details": err.Error()})
+		return
+	}
+
+	if err := storage.InsertCheckoutSession(b.db, sess.ID, uid, b.provider.Name()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": sess.ID, "url": sess.URL})
+}
+
+// GET /api/v1/billing/credits
+// Available packs, current balance and purchase history
+func (b *BillingController) Credits(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	balance, err := storage.CreditBalance(b.db, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	purchases, err := storage.ListCreditPurchases(b.db, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":   balance,
+		"packs":     b.cfg.CreditPacks,
+		"purchases": purchases,
+	})
+}
+
+// Request body: { "pack": "trip" }
+type buyCreditsReq struct {
+	Pack string `json:"pack" binding:"required"`
+}
+
+// POST /api/v1/billing/credits/checkout
+// One-off checkout for a credit pack; credits are granted by the webhook
+func (b *BillingController) BuyCredits(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req buyCreditsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": err.Error()})
+		return
+	}
+	pack, ok := b.cfg.Pack(req.Pack)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown_pack"})
+		return
+	}
+
+	email, err := storage.GetUserEmail(b.db, uid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sess, err := b.provider.CreateCheckoutSession(c.Request.Context(), services.CheckoutRequest{
+		UserID:  uid,
+		Email:   email,
+		Mode:    services.CheckoutPayment,
+		PriceID: pack.PriceID,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "checkout_failed", "details": err.Error()})
+		return
+	}
+
+	if err := storage.InsertCreditPurchase(b.db, sess.ID, uid, pack.ID, pack.Credits, b.provider.Name()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": sess.ID, "url": sess.URL, "pack": pack})
+}
+
+// POST /api/v1/billing/webhook
+// Called by the payment provider (no session cookie; authenticated by signature)
+func (b *BillingController) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	ev, err := b.provider.ParseWebhook(payload, c.GetHeader(b.provider.SignatureHeader()))
+	if errors.Is(err, services.ErrBadSignature) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_signature"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": err.Error()})
+		return
+	}
+
+	if ev.Type == "" || ev.ID == "" {
+		// not an event we act on; acknowledge so the provider stops retrying
+		c.JSON(http.StatusOK, gin.H{"ok": true, "ignored": true})
+		return
+	}
+
+	fresh, err := storage.RecordBillingEvent(b.db, ev.ID, b.provider.Name(), ev.Type)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	if !fresh {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "duplicate": true})
+		return
+	}
+
+	if err := b.applyEvent(ev); err != nil {
+		// un-see it so the provider's retry gets processed
+		_ = storage.ForgetBillingEvent(b.db, ev.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// applyEvent performs the state change for a newly seen webhook event.
+func (b *BillingController) applyEvent(ev services.BillingEvent) error {
+	switch ev.Type {
+	case services.BillingCheckoutCompleted:
+		if !ev.Paid {
+			break
+		}
+
+		// credit pack purchase?
+		expires := time.Now().AddDate(0, 0, b.cfg.CreditExpiryDays)
+		err := storage.MarkCreditPurchasePaid(b.db, ev.SessionID, expires)
+		if err == nil {
+			break
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		// a pack that was already paid must never fall through to the upgrade
+		isPack, err := storage.IsCreditPurchase(b.db, ev.SessionID)
+		if err != nil {
+			return err
+		}
+		if isPack {
+			break
+		}
+
+		// otherwise a subscription; only sessions we created can upgrade anyone,
+		// client_reference_id alone is never trusted
+		uid, err := storage.CompleteCheckoutSession(b.db, ev.SessionID)
+		if err == sql.ErrNoRows {
+			log.Printf("billing: ignoring checkout %s we did not create (client_reference_id %q)", ev.SessionID, ev.UserID)
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := storage.SetUserTier(b.db, uid, storage.TierPro, ev.CustomerID); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+	case services.BillingSubscriptionEnded:
+		if err := storage.SetTierByCustomer(b.db, ev.CustomerID, storage.TierFree); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+	return nil
+}
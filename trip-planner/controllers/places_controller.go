@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/config"
+	"trip-planner/services"
+)
+
+type PlacesController struct {
+	cfg config.Config
+	geo services.Geocoder
+}
+
+func NewPlacesController(cfg config.Config, geo services.Geocoder) *PlacesController {
+	return &PlacesController{cfg: cfg, geo: geo}
+}
+
+// GET /api/v1/places/autocomplete?q=kand&country=LK
+func (p *PlacesController) Autocomplete(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if len(q) < 2 || len(q) > 80 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": "q must be 2-80 characters"})
+		return
+	}
+
+	code := c.DefaultQuery("country", p.cfg.DefaultCountry)
+	country, ok := p.cfg.Country(code)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_country", "details": code})
+		return
+	}
+
+	out, err := p.geo.Autocomplete(c.Request.Context(), q, country)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "autocomplete_failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": out})
+}
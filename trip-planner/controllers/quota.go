@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"trip-planner/config"
+	"trip-planner/storage"
+)
+
+// generationLimit resolves the effective limit: admin override, then tier default.
+func generationLimit(cfg config.Config, u storage.Usage) int {
+	if u.QuotaOverride != nil {
+		return *u.QuotaOverride
+	}
+	if u.Tier == storage.TierPro {
+		return cfg.ProLimit
+	}
+	if cfg.FreeLimit <= 0 {
+		return 2 // default
+	}
+	return cfg.FreeLimit
+}
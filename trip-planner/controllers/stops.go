@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/models"
+	"trip-planner/services"
+	"trip-planner/storage"
+)
+
+// fetchStops loads places and weather for every stop concurrently.
+// On failure it returns the ledger outcome of the first error.
+func (t *TripController) fetchStops(ctx context.Context, route []models.TripStop, country models.Country, interests []string) ([]services.StopContext, string, error) {
+	out := make([]services.StopContext, len(route))
+	placesErr := make([]error, len(route))
+	weatherErr := make([]error, len(route))
+
+	var wg sync.WaitGroup
+	for i, stop := range route {
+		out[i] = services.StopContext{City: stop.City, Nights: stop.Nights}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			out[i].Places, placesErr[i] = t.places.GetPlacesByCity(ctx, stop.City, country, interests)
+		}()
+		go func() {
+			defer wg.Done()
+			out[i].Weather, weatherErr[i] = t.weather.GetCityWeather(ctx, stop.City, country)
+		}()
+	}
+	wg.Wait()
+
+	for i := range route {
+		if placesErr[i] != nil {
+			return nil, storage.OutcomePlacesFailed, placesErr[i]
+		}
+		if weatherErr[i] != nil {
+			return nil, storage.OutcomeWeatherFailed, weatherErr[i]
+		}
+	}
+	return out, "", nil
+}
+
+// fetchDetails loads place details for the top places of every stop and
+// marks closures on the trip dates. Details only enrich the plan, so lookup
+// failures are logged and skipped.
+func (t *TripController) fetchDetails(ctx context.Context, stops []services.StopContext, dates []time.Time) {
+	limit := t.cfg.PlaceDetailsLimit
+	if limit <= 0 {
+		return
+	}
+
+	found := make([][]*services.PlaceDetails, len(stops))
+	var wg sync.WaitGroup
+	for i := range stops {
+		top, _ := services.SlimPlaces(stops[i].Places, limit)["top_places"].([]map[string]any)
+		found[i] = make([]*services.PlaceDetails, len(top))
+		for j, p := range top {
+			id, _ := p["place_id"].(string)
+			if id == "" {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d, err := t.places.GetPlaceDetails(ctx, id)
+				if err != nil {
+					log.Printf("place details %s: %v", id, err)
+					return
+				}
+				d.ClosedOn = d.ClosedDates(dates)
+				found[i][j] = &d
+			}()
+		}
+	}
+	wg.Wait()
+
+	for i := range stops {
+		for _, d := range found[i] {
+			if d != nil {
+				stops[i].Details = append(stops[i].Details, *d)
+			}
+		}
+	}
+}
+
+// allDetails flattens the per-stop details for EnrichItinerary.
+func allDetails(stops []services.StopContext) []services.PlaceDetails {
+	var out []services.PlaceDetails
+	for _, s := range stops {
+		out = append(out, s.Details...)
+	}
+	return out
+}
+
+// tripDates lists the dates of the trip, or nil without a start date.
+func tripDates(req models.TripRequest) []time.Time {
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return nil
+	}
+	dates := make([]time.Time, req.Days)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i)
+	}
+	return dates
+}
+
+// resolveRoute geocodes every stop and rewrites the request with canonical
+// names and place IDs, so typos share places/weather caches and plan hashes.
+// Writes a 400/502 and returns false on failure.
+func (t *TripController) resolveRoute(c *gin.Context, req *models.TripRequest, country models.Country) ([]models.GeoLocation, bool) {
+	route := req.Route()
+	locs := make([]models.GeoLocation, len(route))
+	for i, stop := range route {
+		loc, err := t.geo.Resolve(c.Request.Context(), stop.City, stop.PlaceID, country)
+		if errors.Is(err, services.ErrPlaceNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown_destination", "details": stop.City})
+			return nil, false
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "geocode_failed", "details": err.Error()})
+			return nil, false
+		}
+		locs[i] = loc
+	}
+
+	for i := range req.Stops {
+		req.Stops[i].City = locs[i].Name
+		req.Stops[i].PlaceID = locs[i].PlaceID
+	}
+	req.Destination = locs[0].Name
+	req.PlaceID = locs[0].PlaceID
+	return locs, true
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"trip-planner/config"
 	"trip-planner/models"
 	"trip-planner/services"
+	"trip-planner/storage"
 	"trip-planner/utils"
 )
 
@@ -23,6 +26,7 @@ type TripController struct {
 	ai      *services.AIService
 	places  *services.PlacesService
 	weather *services.WeatherService
+	geo     services.Geocoder
 
 	store *utils.JSONStore[models.TripPlan]
 }
@@ -33,6 +37,7 @@ func NewTripController(
 	ai *services.AIService,
 	places *services.PlacesService,
 	weather *services.WeatherService,
+	geo services.Geocoder,
 ) *TripController {
 	return &TripController{
 		cfg:     cfg,
@@ -40,6 +45,7 @@ func NewTripController(
 		ai:      ai,
 		places:  places,
 		weather: weather,
+		geo:     geo,
 		store:   utils.NewJSONStore[models.TripPlan](cfg.PlansFile),
 	}
 }
@@ -115,13 +121,32 @@ func (t *TripController) CreatePlan(c *gin.Context) {
 		return
 	}
 
-	// defaults
+	// defaults: saved preferences first, then built-ins
+	t.applyPreferences(uid, &req)
 	if req.Budget == "" {
 		req.Budget = "mid"
 	}
 	if req.Pace == "" {
 		req.Pace = "balanced"
 	}
+	if !validateRequest(c, &req) {
+		return
+	}
+	if req.Language == "" {
+		req.Language = t.cfg.DefaultLanguage
+	}
+	if !t.cfg.SupportsLanguage(req.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_language", "details": req.Language})
+		return
+	}
+	country, ok := t.resolveCountry(c, &req)
+	if !ok {
+		return
+	}
+	locations, ok := t.resolveRoute(c, &req, country)
+	if !ok {
+		return
+	}
 
 	hash := hashTripRequest(req)
 
@@ -134,42 +159,58 @@ func (t *TripController) CreatePlan(c *gin.Context) {
 	// ✅ If same hash for same user => return saved plan (NO AI)
 	for _, p := range all {
 		if p.UserID == uid && p.InputHash == hash {
+			t.recordAttempt(uid, p.ID, services.AIUsage{}, 0, storage.OutcomeSuccess, true)
 			c.JSON(http.StatusOK, p)
 			return
 		}
 	}
 
 	// ✅ Enforce quota only when we REALLY need AI
-	if err := t.ensureFreeQuota(c, uid); err != nil {
-		// ensureFreeQuota already wrote response
-		return
-	}
-
-	// Places (cached by city)
-	places, err := t.places.GetPlacesByCity(c.Request.Context(), req.Destination)
+	creditID, err := t.ensureFreeQuota(c, uid)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "places_failed", "details": err.Error()})
+		// ensureFreeQuota already wrote response
 		return
 	}
+	saved := false
+	defer func() {
+		if !saved {
+			t.refundCredit(creditID)
+		}
+	}()
 
-	// Weather (cached)
-	weather, err := t.weather.GetCityWeather(c.Request.Context(), req.Destination)
+	// Places + weather for every stop (cached by city, fetched concurrently)
+	stops, outcome, err := t.fetchStops(c.Request.Context(), req.Route(), country, req.Interests)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "weather_failed", "details": err.Error()})
+		t.recordAttempt(uid, "", services.AIUsage{}, 0, outcome, false)
+		c.JSON(http.StatusBadGateway, gin.H{"error": outcome, "details": err.Error()})
 		return
 	}
+	t.fetchDetails(c.Request.Context(), stops, tripDates(req))
 
 	// AI (only once)
-	itinerary, err := t.ai.GenerateTrip(c.Request.Context(), req, places, weather)
+	started := time.Now()
+	itinerary, usage, err := t.ai.GenerateTrip(c.Request.Context(), req, country, stops)
+	latency := time.Since(started)
 	if err != nil {
+		t.recordAttempt(uid, "", usage, latency, storage.OutcomeAIFailed, false)
+		if errors.Is(err, services.ErrPromptTooLarge) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "request_too_large", "details": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadGateway, gin.H{"error": "ai_failed", "details": err.Error()})
 		return
 	}
 
+	// Hours, prices, photos on matching items; flag closures
+	services.EnrichItinerary(itinerary, allDetails(stops))
+
 	// ✅ Attach contexts so frontend can show WeatherCard etc.
 	// itinerary is map[string]any (recommended). If your AI returns map, great.
-	itinerary["weather"] = weather
-	itinerary["places"] = places
+	itinerary["weather"] = stops[0].Weather
+	itinerary["places"] = stops[0].Places
+	if len(stops) > 1 {
+		itinerary["stop_context"] = stops
+	}
 
 	now := time.Now().Unix()
 	plan := models.TripPlan{
@@ -178,18 +219,28 @@ func (t *TripController) CreatePlan(c *gin.Context) {
 		InputHash: hash,
 		Request:   req,
 		Itinerary: itinerary,
+		Locations: locations,
 		CreatedAt: now,
 		UpdatedAt: now,
+
+		PromptVersion: usage.PromptVersion,
 	}
 
-	all = append(all, plan)
-	if err := t.store.WriteAll(all); err != nil {
+	err = t.store.Update(func(all []models.TripPlan) []models.TripPlan {
+		return append(all, plan)
+	})
+	if err != nil {
+		t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeSaveFailed, false)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
 		return
 	}
 
 	// ✅ count usage only after successful save
-	_ = t.incrementUsage(uid)
+	saved = true
+	if err := t.incrementUsage(uid); err != nil {
+		log.Printf("count generation for %s: %v", uid, err)
+	}
+	t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeSuccess, false)
 
 	c.JSON(http.StatusOK, plan)
 }
@@ -209,120 +260,270 @@ func (t *TripController) Regenerate(c *gin.Context) {
 		return
 	}
 
+	t.applyPreferences(uid, &req)
 	if req.Budget == "" {
 		req.Budget = "mid"
 	}
 	if req.Pace == "" {
 		req.Pace = "balanced"
 	}
-
-	hash := hashTripRequest(req)
-
-	all, err := t.store.ReadAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+	if !validateRequest(c, &req) {
 		return
 	}
-
-	// ✅ Enforce quota (regen also consumes a generation)
-	if err := t.ensureFreeQuota(c, uid); err != nil {
+	if req.Language == "" {
+		req.Language = t.cfg.DefaultLanguage
+	}
+	if !t.cfg.SupportsLanguage(req.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_language", "details": req.Language})
+		return
+	}
+	country, ok := t.resolveCountry(c, &req)
+	if !ok {
 		return
 	}
+	locations, ok := t.resolveRoute(c, &req, country)
+	if !ok {
+		return
+	}
+
+	hash := hashTripRequest(req)
 
-	places, err := t.places.GetPlacesByCity(c.Request.Context(), req.Destination)
+	// ✅ Enforce quota (regen also consumes a generation)
+	creditID, err := t.ensureFreeQuota(c, uid)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "places_failed", "details": err.Error()})
 		return
 	}
+	saved := false
+	defer func() {
+		if !saved {
+			t.refundCredit(creditID)
+		}
+	}()
 
-	weather, err := t.weather.GetCityWeather(c.Request.Context(), req.Destination)
+	stops, outcome, err := t.fetchStops(c.Request.Context(), req.Route(), country, req.Interests)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "weather_failed", "details": err.Error()})
+		t.recordAttempt(uid, "", services.AIUsage{}, 0, outcome, false)
+		c.JSON(http.StatusBadGateway, gin.H{"error": outcome, "details": err.Error()})
 		return
 	}
+	t.fetchDetails(c.Request.Context(), stops, tripDates(req))
 
-	itinerary, err := t.ai.GenerateTrip(c.Request.Context(), req, places, weather)
+	started := time.Now()
+	itinerary, usage, err := t.ai.GenerateTrip(c.Request.Context(), req, country, stops)
+	latency := time.Since(started)
 	if err != nil {
+		t.recordAttempt(uid, "", usage, latency, storage.OutcomeAIFailed, false)
+		if errors.Is(err, services.ErrPromptTooLarge) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "request_too_large", "details": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadGateway, gin.H{"error": "ai_failed", "details": err.Error()})
 		return
 	}
 
-	itinerary["weather"] = weather
-	itinerary["places"] = places
+	services.EnrichItinerary(itinerary, allDetails(stops))
+	itinerary["weather"] = stops[0].Weather
+	itinerary["places"] = stops[0].Places
+	if len(stops) > 1 {
+		itinerary["stop_context"] = stops
+	}
 
 	now := time.Now().Unix()
 
-	// If hash exists for same user, update it
-	for i := range all {
-		if all[i].UserID == uid && all[i].InputHash == hash {
-			all[i].Request = req
-			all[i].Itinerary = itinerary
-			all[i].UpdatedAt = now
-			if err := t.store.WriteAll(all); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
-				return
-			}
-			_ = t.incrementUsage(uid)
-			c.JSON(http.StatusOK, all[i])
-			return
-		}
-	}
-
-	// Otherwise create new
+	// If hash exists for same user, update it; otherwise create new.
+	// Done inside Update so plans saved or purged meanwhile aren't lost.
 	plan := models.TripPlan{
 		ID:        uuid.NewString(),
 		UserID:    uid,
 		InputHash: hash,
 		Request:   req,
 		Itinerary: itinerary,
+		Locations: locations,
 		CreatedAt: now,
 		UpdatedAt: now,
-	}
-	all = append(all, plan)
-	if err := t.store.WriteAll(all); err != nil {
+
+		PromptVersion: usage.PromptVersion,
+	}
+	err = t.store.Update(func(all []models.TripPlan) []models.TripPlan {
+		for i := range all {
+			if all[i].UserID == uid && all[i].InputHash == hash {
+				all[i].Request = req
+				all[i].Itinerary = itinerary
+				all[i].Locations = locations
+				all[i].Translations = nil // translations of the old itinerary
+				all[i].UpdatedAt = now
+				all[i].PromptVersion = usage.PromptVersion
+				plan = all[i]
+				return all
+			}
+		}
+		return append(all, plan)
+	})
+	if err != nil {
+		t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeSaveFailed, false)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
 		return
 	}
-	_ = t.incrementUsage(uid)
+	saved = true
+	if err := t.incrementUsage(uid); err != nil {
+		log.Printf("count generation for %s: %v", uid, err)
+	}
+	t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeSuccess, false)
 
 	c.JSON(http.StatusOK, plan)
 }
 
-func (t *TripController) ensureFreeQuota(c *gin.Context, uid string) error {
-	limit := t.cfg.FreeLimit
-	if limit <= 0 {
-		limit = 2 // default
+type translateRequest struct {
+	Language string `json:"language" binding:"required"`
+}
+
+// POST /api/v1/trip/plan/:id/translate
+// Translates the stored itinerary text (no regeneration, no quota). Cached per language on the plan.
+func (t *TripController) Translate(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var body translateRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": err.Error()})
+		return
+	}
+	if !t.cfg.SupportsLanguage(body.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_language", "details": body.Language})
+		return
 	}
 
+	id := c.Param("id")
+	all, err := t.store.ReadAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "read_failed", "details": err.Error()})
+		return
+	}
+	var plan *models.TripPlan
+	for i := range all {
+		if all[i].ID == id && all[i].UserID == uid {
+			plan = &all[i]
+			break
+		}
+	}
+	if plan == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+
+	original := plan.Request.Language
+	if original == "" {
+		original = t.cfg.DefaultLanguage
+	}
+	if body.Language == original {
+		c.JSON(http.StatusOK, gin.H{"plan_id": plan.ID, "language": original, "itinerary": plan.Itinerary})
+		return
+	}
+	if tr, ok := plan.Translations[body.Language]; ok {
+		c.JSON(http.StatusOK, gin.H{"plan_id": plan.ID, "language": body.Language, "itinerary": tr})
+		return
+	}
+
+	started := time.Now()
+	translated, usage, err := t.ai.TranslatePlan(c.Request.Context(), plan.Itinerary, body.Language)
+	latency := time.Since(started)
+	if err != nil {
+		t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeAIFailed, false)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "ai_failed", "details": err.Error()})
+		return
+	}
+
+	// Store only this translation on the current copy of the plan; the
+	// snapshot above may be stale after the AI call.
+	found := false
+	err = t.store.Update(func(all []models.TripPlan) []models.TripPlan {
+		for i := range all {
+			if all[i].ID == id && all[i].UserID == uid {
+				if all[i].Translations == nil {
+					all[i].Translations = map[string]any{}
+				}
+				all[i].Translations[body.Language] = translated
+				all[i].UpdatedAt = time.Now().Unix()
+				found = true
+				break
+			}
+		}
+		return all
+	})
+	if err != nil {
+		t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeSaveFailed, false)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "save_failed", "details": err.Error()})
+		return
+	}
+	if !found {
+		t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeSaveFailed, false)
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	t.recordAttempt(uid, plan.ID, usage, latency, storage.OutcomeTranslated, false)
+
+	c.JSON(http.StatusOK, gin.H{"plan_id": plan.ID, "language": body.Language, "itinerary": translated})
+}
+
+// ensureFreeQuota checks the allowance. Once it is used up it reserves one
+// prepaid credit and returns the purchase it came from ("" when none was
+// needed); refundCredit gives it back if the generation fails.
+// On refusal it writes the response and returns an error.
+func (t *TripController) ensureFreeQuota(c *gin.Context, uid string) (string, error) {
 	// If DB not configured, allow (but you should configure)
 	if t.db == nil {
-		return nil
+		return "", nil
 	}
 
 	// Ensure usage row exists
 	now := time.Now().Unix()
 	_, _ = t.db.Exec(`INSERT OR IGNORE INTO usage (user_id, generations, updated_at) VALUES (?, ?, ?)`, uid, 0, now)
 
-	var gens int
-	err := t.db.QueryRow(`SELECT generations FROM usage WHERE user_id = ?`, uid).Scan(&gens)
+	usage, err := storage.GetUsage(t.db, uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "usage_read_failed", "details": err.Error()})
-		return err
+		return "", err
+	}
+	limit := generationLimit(t.cfg, usage)
+	if usage.Generations < limit {
+		return "", nil
 	}
 
-	if gens >= limit {
-		c.JSON(http.StatusPaymentRequired, gin.H{
-			"error":   "limit_reached",
-			"details": "Free limit reached. Please upgrade to generate more itineraries.",
-			"limit":   limit,
-			"used":    gens,
-		})
-		return sql.ErrNoRows // any non-nil to stop flow
+	// free allowance used up: a prepaid credit still allows generating
+	creditID, err := storage.ConsumeCredit(t.db, uid)
+	if err == nil {
+		return creditID, nil
 	}
+	if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "usage_read_failed", "details": err.Error()})
+		return "", err
+	}
+
+	t.recordAttempt(uid, "", services.AIUsage{}, 0, storage.OutcomeLimitReached, false)
+	c.JSON(http.StatusPaymentRequired, gin.H{
+		"error":   "limit_reached",
+		"details": "Free limit reached. Please upgrade to generate more itineraries.",
+		"limit":   limit,
+		"used":    usage.Generations,
+		"tier":    usage.Tier,
+	})
+	return "", sql.ErrNoRows // any non-nil to stop flow
+}
 
-	return nil
+// refundCredit returns a credit reserved by ensureFreeQuota.
+func (t *TripController) refundCredit(creditID string) {
+	if creditID == "" {
+		return
+	}
+	if err := storage.RefundCredit(t.db, creditID); err != nil {
+		log.Printf("refund credit %s: %v", creditID, err)
+	}
 }
 
+// incrementUsage counts a generation (any credit was already taken by ensureFreeQuota).
 func (t *TripController) incrementUsage(uid string) error {
 	if t.db == nil {
 		return nil
@@ -332,9 +533,132 @@ func (t *TripController) incrementUsage(uid string) error {
 	return err
 }
 
+// validateRequest checks the party isn't empty and the route fits in the trip,
+// and fills Destination from the first stop. Writes a 400 and returns false otherwise.
+func validateRequest(c *gin.Context, req *models.TripRequest) bool {
+	if req.Party != nil && req.Party.Count() < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": "party needs at least one traveller"})
+		return false
+	}
+	if len(req.Stops) == 0 {
+		return true
+	}
+	nights := 0
+	for _, s := range req.Stops {
+		nights += s.Nights
+	}
+	if nights > req.Days {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "details": "stop nights exceed trip days"})
+		return false
+	}
+	if req.Destination == "" {
+		req.Destination = req.Stops[0].City
+	}
+	return true
+}
+
+// resolveCountry defaults req.Country and checks it is one we serve.
+// Writes a 400 and returns false otherwise.
+func (t *TripController) resolveCountry(c *gin.Context, req *models.TripRequest) (models.Country, bool) {
+	if req.Country == "" {
+		req.Country = t.cfg.DefaultCountry
+	}
+	country, ok := t.cfg.Country(req.Country)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_country", "details": req.Country})
+		return models.Country{}, false
+	}
+	req.Country = country.Code
+	return country, true
+}
+
+// applyPreferences fills empty request fields from the user's saved
+// preferences and attaches the traveller profile for the prompt.
+func (t *TripController) applyPreferences(uid string, req *models.TripRequest) {
+	req.Traveller = nil // server-side only
+	if t.db == nil {
+		return
+	}
+	prefs, err := storage.GetPreferences(t.db, uid)
+	if err != nil {
+		return
+	}
+	if req.Budget == "" {
+		req.Budget = prefs.Budget
+	}
+	if req.Pace == "" {
+		req.Pace = prefs.Pace
+	}
+	if len(req.Interests) == 0 {
+		req.Interests = prefs.Interests
+	}
+	if req.Language == "" {
+		req.Language = prefs.Language
+	}
+	req.Traveller = prefs.Traveller()
+}
+
+// recordAttempt appends a generation attempt to the usage ledger.
+// Ledger failures never block the request.
+func (t *TripController) recordAttempt(uid, planID string, usage services.AIUsage, latency time.Duration, outcome string, cacheHit bool) {
+	if t.db == nil {
+		return
+	}
+	model := usage.Model
+	if model == "" && !cacheHit {
+		model = t.cfg.OpenAIModel
+	}
+	_ = storage.InsertLedgerEntry(t.db, storage.LedgerEntry{
+		UserID:           uid,
+		PlanID:           planID,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		LatencyMs:        latency.Milliseconds(),
+		CostUSD:          usage.Cost(t.cfg.OpenAIInputCostPer1M, t.cfg.OpenAIOutputCostPer1M),
+		Outcome:          outcome,
+		CacheHit:         cacheHit,
+	})
+}
+
+// Every plan saved before requests carried a country or language was a
+// Sri Lanka plan in English; those values add nothing to the hash so the
+// old fingerprints still match.
+const (
+	legacyCountry  = "LK"
+	legacyLanguage = "en"
+)
+
 func hashTripRequest(req models.TripRequest) string {
-	// stable hash: serialize important fields
+	// stable hash: serialize important fields; newer fields are appended as suffixes
 	data := req.Destination + "|" + req.StartDate + "|" + itoa(req.Days) + "|" + req.Budget + "|" + req.Pace + "|" + join(req.Interests) + "|" + req.Notes
+	if req.Country != "" && req.Country != legacyCountry {
+		data += "|country:" + req.Country
+	}
+	if req.Language != "" && req.Language != legacyLanguage {
+		data += "|lang:" + req.Language
+	}
+	if tp := req.Traveller; tp != nil {
+		withKids := "0"
+		if tp.WithKids {
+			withKids = "1"
+		}
+		data += "|" + join(tp.Dietary) + "|" + tp.Mobility + "|" + withKids + "|" + tp.Currency
+	}
+	if len(req.Stops) > 0 {
+		stops := make([]string, len(req.Stops))
+		for i, st := range req.Stops {
+			stops[i] = st.City + ":" + itoa(st.Nights)
+		}
+		data += "|stops:" + join(stops)
+	}
+	if p := req.Party; p != nil {
+		ages := make([]string, len(p.ChildrenAges))
+		for i, a := range p.ChildrenAges {
+			ages[i] = itoa(a)
+		}
+		data += "|party:" + itoa(p.Adults) + "/" + join(ages) + "/" + itoa(p.Seniors) + "/" + join(p.Accessibility)
+	}
 	sum := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"trip-planner/models"
+)
+
+func TestHashTripRequestKeepsLegacyFingerprint(t *testing.T) {
+	req := models.TripRequest{
+		Destination: "Kandy",
+		StartDate:   "2026-03-02",
+		Days:        3,
+		Budget:      "mid",
+		Pace:        "balanced",
+		Interests:   []string{"culture", "food"},
+		Notes:       "no early starts",
+	}
+	sum := sha256.Sum256([]byte("Kandy|2026-03-02|3|mid|balanced|culture,food|no early starts"))
+	legacy := hex.EncodeToString(sum[:])
+
+	if got := hashTripRequest(req); got != legacy {
+		t.Fatalf("bare request: hash changed from the pre-country format")
+	}
+	req.Country, req.Language = "LK", "en"
+	if got := hashTripRequest(req); got != legacy {
+		t.Fatalf("LK/en request: hash changed from the pre-country format")
+	}
+
+	req.Country = "TH"
+	th := hashTripRequest(req)
+	req.Language = "de"
+	if th == legacy || hashTripRequest(req) == th {
+		t.Fatalf("country and language must change the hash")
+	}
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/config"
+	"trip-planner/storage"
+)
+
+type UsageController struct {
+	cfg config.Config
+	db  *sql.DB
+}
+
+func NewUsageController(cfg config.Config, db *sql.DB) *UsageController {
+	return &UsageController{cfg: cfg, db: db}
+}
+
+// GET /api/v1/usage
+// Quota + ledger totals + recent attempts for the logged user
+func (u *UsageController) Summary(c *gin.Context) {
+	uid := c.GetString("uid")
+	if uid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	usage, err := storage.GetUsage(u.db, uid)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "usage_read_failed", "details": err.Error()})
+		return
+	}
+
+	summary, err := storage.SummarizeLedger(u.db, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "usage_read_failed", "details": err.Error()})
+		return
+	}
+
+	recent, err := storage.ListLedger(u.db, uid, 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "usage_read_failed", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tier":    usage.Tier,
+		"limit":   generationLimit(u.cfg, usage),
+		"used":    usage.Generations,
+		"summary": summary,
+		"recent":  recent,
+	})
+}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"log"
+	"time"
+	_ "time/tzdata" // country timezones; the alpine runtime image has no zoneinfo
 
 	"github.com/gin-gonic/gin"
 
 	"trip-planner/config"
+	"trip-planner/models"
 	"trip-planner/routes"
 	"trip-planner/services"
 	"trip-planner/storage"
+	"trip-planner/utils"
 )
 
 func main() {
@@ -24,11 +29,50 @@ func main() {
 		_ = db.Close()
 	}(db)
 
+	// ---- JWT keys ----
+	keys, err := loadKeyring(cfg)
+	if err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+
 	// ---- Services ----
-	aiSvc := services.NewAIService(cfg.OpenAIKey, cfg.OpenAIModel)
-	placesSvc := services.NewPlacesService(cfg.GoogleMapsKey, cfg.PlacesCacheHours)
+	prompts, err := services.NewPromptTemplates(cfg.PromptTemplatesDir, cfg.PromptTemplate)
+	if err != nil {
+		log.Fatalf("prompt templates: %v", err)
+	}
+	aiSvc := services.NewAIService(cfg.OpenAIKey, cfg.OpenAIModel, services.PromptConfig{
+		Templates:     prompts,
+		PlaceLimit:    cfg.PromptPlaceLimit,
+		ContextTokens: cfg.OpenAIContextTokens,
+		OutputReserve: cfg.OpenAIOutputReserve,
+	})
+	placesSvc := services.NewPlacesService(cfg.GoogleMapsKey, cfg.PlacesCacheHours, cfg.PlaceDetailsCacheHours)
 	weatherSvc := services.NewWeatherService(cfg.OpenWeatherKey, cfg.WeatherCacheHours)
-	authSvc := services.NewAuthService(cfg.GoogleClientID)
+	geoSvc := services.NewGoogleGeocoder(cfg.GoogleMapsKey, cfg.PlacesCacheHours)
+	oidc := make([]services.OIDCConfig, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		oidc = append(oidc, services.OIDCConfig{Name: p.Name, Issuer: p.Issuer, ClientID: p.ClientID})
+	}
+	authSvc := services.NewAuthService(cfg.GoogleClientID, oidc)
+	mailer := services.NewEmailSender(cfg.EmailSender, services.EmailConfig{
+		From:      cfg.EmailFrom,
+		SMTPHost:  cfg.SMTPHost,
+		SMTPPort:  cfg.SMTPPort,
+		SMTPUser:  cfg.SMTPUser,
+		SMTPPass:  cfg.SMTPPass,
+		OutboxDir: cfg.EmailOutboxDir,
+	})
+	billing := services.NewBillingProvider(cfg.BillingProvider, services.BillingConfig{
+		SecretKey:     cfg.StripeSecretKey,
+		WebhookSecret: cfg.StripeWebhookSecret,
+		PriceID:       cfg.StripePriceID,
+		SuccessURL:    cfg.BillingSuccessURL,
+		CancelURL:     cfg.BillingCancelURL,
+	})
+
+	// ---- Background jobs ----
+	// purge accounts whose deletion grace period ended
+	go storage.RunAccountPurger(db, utils.NewJSONStore[models.TripPlan](cfg.PlansFile), time.Hour)
 
 	// ---- Gin ----
 	r := gin.New()
@@ -42,9 +86,45 @@ func main() {
 		aiSvc,
 		placesSvc,
 		weatherSvc,
+		geoSvc,
 		authSvc,
+		billing,
+		mailer,
+		keys,
 	)
 
 	log.Printf("Trip Planner API running on :%s", cfg.AppPort)
 	_ = r.Run(":" + cfg.AppPort)
 }
+
+// loadKeyring builds the JWT keyring from JWT_KEYS, falling back to a single
+// HS256 key from JWT_SECRET.
+func loadKeyring(cfg config.Config) (*utils.Keyring, error) {
+	if len(cfg.JWTKeys) == 0 {
+		if cfg.JWTSecret == "" {
+			return nil, errors.New("set JWT_SECRET or JWT_KEYS")
+		}
+		k, err := utils.NewHMACKey("default", cfg.JWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		return utils.NewKeyring("default", k)
+	}
+
+	keys := make([]*utils.SigningKey, 0, len(cfg.JWTKeys))
+	for _, jk := range cfg.JWTKeys {
+		var k *utils.SigningKey
+		var err error
+		if jk.Alg == utils.AlgHS256 {
+			k, err = utils.NewHMACKey(jk.ID, jk.Material)
+		} else {
+			k, err = utils.LoadPEMKey(jk.ID, jk.Alg, jk.Material)
+		}
+		if err != nil {
+			return nil, err
+		}
+		k.NotAfter = jk.NotAfter
+		keys = append(keys, k)
+	}
+	return utils.NewKeyring(cfg.JWTActiveKID, keys...)
+}
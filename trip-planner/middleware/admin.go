@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"trip-planner/storage"
+)
+
+// RequireAdmin must run after RequireAuth. Admins are configured by email
+// allowlist and must be signed in with a session: API keys never grant admin.
+func RequireAdmin(db *sql.DB, adminEmails []string) gin.HandlerFunc {
+	allowed := map[string]bool{}
+	for _, e := range adminEmails {
+		allowed[strings.ToLower(strings.TrimSpace(e))] = true
+	}
+
+	return func(c *gin.Context) {
+		uid := c.GetString("uid")
+		if uid == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+		if c.GetString("auth_method") != "session" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "session_required"})
+			c.Abort()
+			return
+		}
+		email, err := storage.GetUserEmail(db, uid)
+		if err != nil || !allowed[strings.ToLower(email)] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			c.Abort()
+			return
+		}
+		c.Set("admin_email", email)
+		c.Next()
+	}
+}
+
+// RejectBanned must run after RequireAuth.
+func RejectBanned(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		banned, err := storage.IsBanned(db, c.GetString("uid"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "user_read_failed"})
+			c.Abort()
+			return
+		}
+		if banned {
+			c.JSON(http.StatusForbidden, gin.H{"error": "banned"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
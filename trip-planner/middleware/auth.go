@@ -1,26 +1,101 @@
 package middleware
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+
+	"trip-planner/storage"
 	"trip-planner/utils"
 )
 
-func RequireAuth(jwtSecret string) gin.HandlerFunc {
+// last_seen_at / last_used_at are only written when older than this, to avoid a write per request
+const sessionTouchEvery = 5 * time.Minute
+
+// RequireAuth accepts either the "session" cookie or an Authorization: Bearer
+// credential (a personal API key, or an access token for non-browser clients).
+// It sets "uid", "sid" (session only) and "auth_method" ("session" | "api_key").
+func RequireAuth(keys *utils.Keyring, db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie("session")
-		if err != nil || token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error":"unauthorized"})
+		bearer := bearerToken(c)
+		if strings.HasPrefix(bearer, storage.APIKeyPrefix) {
+			apiKeyAuth(c, db, bearer)
+			return
+		}
+
+		token := bearer
+		if token == "" {
+			token, _ = c.Cookie("session")
+		}
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+		uid, sid, err := utils.ParseUserJWT(keys, token)
+		if errors.Is(err, utils.ErrTokenExpired) {
+			// client should POST /auth/refresh and retry
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token_expired"})
 			c.Abort()
 			return
 		}
-		uid, err := utils.ParseUserJWT(jwtSecret, token)
 		if err != nil || uid == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error":"unauthorized"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		// server-side session must exist and not be revoked
+		sess, err := storage.GetActiveSession(db, sid, uid)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session_revoked"})
 			c.Abort()
 			return
 		}
+		if time.Since(time.Unix(sess.LastSeenAt, 0)) > sessionTouchEvery {
+			_ = storage.TouchSession(db, sid, c.ClientIP())
+		}
+
 		c.Set("uid", uid)
+		c.Set("sid", sid)
+		c.Set("auth_method", "session")
 		c.Next()
 	}
 }
+
+func apiKeyAuth(c *gin.Context, db *sql.DB, raw string) {
+	key, err := storage.FindAPIKey(db, raw)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_api_key"})
+		c.Abort()
+		return
+	}
+
+	// read-scoped keys may only call safe methods
+	if !key.HasScope(storage.ScopeWrite) && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+		c.Abort()
+		return
+	}
+
+	if time.Since(time.Unix(key.LastUsedAt, 0)) > sessionTouchEvery {
+		_ = storage.TouchAPIKey(db, key.ID)
+	}
+
+	c.Set("uid", key.UserID)
+	c.Set("api_key_id", key.ID)
+	c.Set("auth_method", "api_key")
+	c.Next()
+}
+
+func bearerToken(c *gin.Context) string {
+	h := c.GetHeader("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
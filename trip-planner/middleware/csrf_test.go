@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CSRF([]string{"https://app.example.com/"}))
+	r.POST("/trip/plan", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/trip/plans", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		cookie  bool
+		want    int
+	}{
+		{"foreign origin rejected", http.MethodPost, map[string]string{"Origin": "https://evil.example"}, true, http.StatusForbidden},
+		{"same host allowed", http.MethodPost, map[string]string{"Origin": "https://api.example.com"}, true, http.StatusNoContent},
+		{"allowlisted origin allowed", http.MethodPost, map[string]string{"Origin": "https://APP.example.com"}, true, http.StatusNoContent},
+		{"referer fallback allowed", http.MethodPost, map[string]string{"Referer": "https://app.example.com/trips/1"}, true, http.StatusNoContent},
+		{"foreign referer rejected", http.MethodPost, map[string]string{"Referer": "https://evil.example/page"}, true, http.StatusForbidden},
+		{"null origin uses referer", http.MethodPost, map[string]string{"Origin": "null", "Referer": "https://evil.example/"}, true, http.StatusForbidden},
+		{"no origin with cookie rejected", http.MethodPost, nil, true, http.StatusForbidden},
+		{"no origin without cookie allowed", http.MethodPost, nil, false, http.StatusNoContent},
+		{"bearer allowed", http.MethodPost, map[string]string{"Origin": "https://evil.example", "Authorization": "Bearer tp_abc"}, true, http.StatusNoContent},
+		{"safe method allowed", http.MethodGet, map[string]string{"Origin": "https://evil.example"}, true, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/trip/plan"
+			if tt.method == http.MethodGet {
+				path = "/trip/plans"
+			}
+			req := httptest.NewRequest(tt.method, "https://api.example.com"+path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: "session", Value: "jwt"})
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+// Country is the destination context a TripRequest is resolved against.
+type Country struct {
+	Code     string `json:"code"`     // ISO 3166-1 alpha-2, e.g. LK
+	Name     string `json:"name"`     // used in Places queries and the prompt
+	Currency string `json:"currency"` // ISO 4217, e.g. LKR
+	Timezone string `json:"timezone"` // IANA, e.g. Asia/Colombo
+	Locale   string `json:"locale"`   // BCP 47, e.g. si-LK
+}
@@ -0,0 +1,11 @@
+package models
+
+// GeoLocation is a destination resolved by the geocoder.
+type GeoLocation struct {
+	Query   string  `json:"query"` // what the user typed
+	PlaceID string  `json:"place_id"`
+	Name    string  `json:"name"` // canonical city / area name
+	Address string  `json:"address"`
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+}
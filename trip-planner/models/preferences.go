@@ -0,0 +1,27 @@
+package models
+
+// UserPreferences are per-user defaults applied to every TripRequest.
+type UserPreferences struct {
+	Budget    string   `json:"budget" binding:"omitempty,oneof=low mid high"`
+	Pace      string   `json:"pace" binding:"omitempty,oneof=chill balanced fast"`
+	Interests []string `json:"interests" binding:"max=20,dive,max=40"`
+	Dietary   []string `json:"dietary" binding:"max=10,dive,max=40"` // e.g. vegetarian, halal, nut allergy
+	Mobility  string   `json:"mobility" binding:"max=200"`           // free text, e.g. "wheelchair, no long stairs"
+	WithKids  bool     `json:"with_kids"`
+	Currency  string   `json:"currency" binding:"omitempty,iso4217"` // preferred display currency
+	Language  string   `json:"language"`                             // itinerary language (ISO 639-1)
+}
+
+// Traveller returns the parts of the preferences the planner needs beyond the
+// request fields, or nil when there is nothing to add.
+func (p UserPreferences) Traveller() *TravellerProfile {
+	if len(p.Dietary) == 0 && p.Mobility == "" && !p.WithKids && p.Currency == "" {
+		return nil
+	}
+	return &TravellerProfile{
+		Dietary:  p.Dietary,
+		Mobility: p.Mobility,
+		WithKids: p.WithKids,
+		Currency: p.Currency,
+	}
+}
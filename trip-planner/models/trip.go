@@ -1,13 +1,69 @@
 package models
 
 type TripRequest struct {
-	Destination string   `json:"destination" binding:"required"`
+	Destination string   `json:"destination" binding:"required_without=Stops"`
+	PlaceID     string   `json:"place_id,omitempty"` // from /places/autocomplete; resolved server-side if empty
+	Country     string   `json:"country" binding:"omitempty,iso3166_1_alpha2"`
 	StartDate   string   `json:"start_date"` // YYYY-MM-DD optional
 	Days        int      `json:"days" binding:"required,min=1,max=30"`
 	Budget      string   `json:"budget"` // low|mid|high
 	Interests   []string `json:"interests"`
 	Pace        string   `json:"pace"` // chill|balanced|fast
 	Notes       string   `json:"notes"`
+	Language    string   `json:"language,omitempty"` // ISO 639-1; defaults to preferences, then DEFAULT_LANGUAGE
+
+	// Ordered multi-city route; Destination alone means a single stop
+	Stops []TripStop `json:"stops,omitempty" binding:"max=10,dive"`
+
+	Party *TravelParty `json:"party,omitempty"`
+
+	// Filled from the user's saved preferences (not by the client)
+	Traveller *TravellerProfile `json:"traveller,omitempty"`
+}
+
+type TripStop struct {
+	City    string `json:"city" binding:"required,max=80"`
+	PlaceID string `json:"place_id,omitempty"`
+	Nights  int    `json:"nights,omitempty" binding:"min=0,max=30"` // 0 = let the planner decide
+}
+
+// Route returns the stops to plan for: Stops if given, else Destination.
+func (r TripRequest) Route() []TripStop {
+	if len(r.Stops) > 0 {
+		return r.Stops
+	}
+	return []TripStop{{City: r.Destination, PlaceID: r.PlaceID}}
+}
+
+// TravelParty describes who is travelling. Omitted means one adult.
+type TravelParty struct {
+	Adults        int      `json:"adults" binding:"min=0,max=20"` // 0 is fine for a seniors-only party
+	ChildrenAges  []int    `json:"children_ages" binding:"max=10,dive,min=0,max=17"`
+	Seniors       int      `json:"seniors" binding:"min=0,max=20"` // 65+, counted separately from adults
+	Accessibility []string `json:"accessibility" binding:"max=10,dive,max=80"`
+}
+
+// Size is the number of travellers (at least 1).
+func (p *TravelParty) Size() int {
+	if p == nil {
+		return 1
+	}
+	if n := p.Count(); n > 1 {
+		return n
+	}
+	return 1
+}
+
+// Count is the number of people actually listed (may be 0).
+func (p *TravelParty) Count() int {
+	return p.Adults + p.Seniors + len(p.ChildrenAges)
+}
+
+type TravellerProfile struct {
+	Dietary  []string `json:"dietary,omitempty"`
+	Mobility string   `json:"mobility,omitempty"`
+	WithKids bool     `json:"with_kids,omitempty"`
+	Currency string   `json:"currency,omitempty"`
 }
 
 type TripPlan struct {
@@ -18,6 +74,16 @@ type TripPlan struct {
 
 	Itinerary any `json:"itinerary"`
 
+	// Translated copies of the itinerary text keyed by language code
+	// (places/weather context is shared with the original)
+	Translations map[string]any `json:"translations,omitempty"`
+
+	// Prompt template version that generated the itinerary
+	PromptVersion string `json:"prompt_version,omitempty"`
+
+	// Geocoded stops (canonical place IDs + coordinates), in route order
+	Locations []GeoLocation `json:"locations,omitempty"`
+
 	// ✅ NEW: for frontend display
 	Weather any `json:"weather,omitempty"`
 	Places  any `json:"places,omitempty"`
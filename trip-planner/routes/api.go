@@ -2,6 +2,7 @@ package routes
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -10,6 +11,7 @@ import (
 	"trip-planner/controllers"
 	"trip-planner/middleware"
 	"trip-planner/services"
+	"trip-planner/utils"
 )
 
 func RegisterRoutes(
@@ -19,49 +21,159 @@ func RegisterRoutes(
 	ai *services.AIService,
 	places *services.PlacesService,
 	weather *services.WeatherService,
+	geo services.Geocoder,
 	authSvc *services.AuthService,
+	billing services.BillingProvider,
+	mailer services.EmailSender,
+	keys *utils.Keyring,
 ) {
 	// -------- Base middleware (recommended) --------
 	r.Use(gin.Recovery())
 
-	// If you are behind reverse proxy (nginx), set trusted proxies properly
-	// For now, disable trusting all proxies:
-	_ = r.SetTrustedProxies(nil)
+	// If you are behind reverse proxy (nginx), set TRUSTED_PROXIES so
+	// ClientIP (rate limits, sessions) sees the real client.
+	// Empty => trust no proxies:
+	if len(cfg.TrustedProxies) > 0 {
+		_ = r.SetTrustedProxies(cfg.TrustedProxies)
+	} else {
+		log.Printf("warning: TRUSTED_PROXIES is empty; behind a proxy every client shares one rate-limit bucket")
+		_ = r.SetTrustedProxies(nil)
+	}
+
+	// CORS on the engine so preflights for any path are answered
+	r.Use(middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAgeSeconds:    cfg.CORSMaxAgeSeconds,
+	}))
 
 	// -------- Versioned API group --------
 	api := r.Group("/api")
 	v1 := api.Group("/v1")
 
+	// Reject cross-site POST/PUT/DELETE riding on the session cookie
+	v1.Use(middleware.CSRF(cfg.AllowedOrigins))
+
+	// -------- Rate limits (per client IP, or per uid once authenticated) --------
+	limiter := middleware.NewMemoryRateLimitStore()
+	limit := func(name string) gin.HandlerFunc {
+		l := cfg.RateLimits[name]
+		return middleware.RateLimitByUser(limiter, name, middleware.RateLimit{
+			Requests: l.Requests,
+			Period:   l.Period,
+			Burst:    l.Burst,
+		})
+	}
+
+	// Public routes share the per-IP default bucket; authed routes take the
+	// default limit after RequireAuth so each user gets their own bucket.
+	public := v1.Group("", limit("default"))
+	authed := v1.Group("", middleware.RequireAuth(keys, db), limit("default"))
+
 	// -------- Health --------
-	v1.GET("/health", func(c *gin.Context) {
+	public.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 
 	// -------- Controllers --------
-	authCtrl := controllers.NewAuthController(db, authSvc, cfg.JWTSecret, cfg.CookieDomain)
+	authCtrl := controllers.NewAuthController(cfg, db, authSvc, mailer, keys)
+
+	tripCtrl := controllers.NewTripController(cfg, db, ai, places, weather, geo)
+
+	placesCtrl := controllers.NewPlacesController(cfg, geo)
+
+	usageCtrl := controllers.NewUsageController(cfg, db)
+
+	adminCtrl := controllers.NewAdminController(cfg, db)
 
-	tripCtrl := controllers.NewTripController(cfg, db, ai, places, weather)
+	billingCtrl := controllers.NewBillingController(cfg, db, billing)
+
+	apiKeyCtrl := controllers.NewAPIKeyController(db)
+
+	accountCtrl := controllers.NewAccountController(cfg, db)
 
 	// -------- Auth routes --------
 	// Frontend sends Google "id_token"
-	v1.POST("/auth/google", authCtrl.GoogleLogin)
+	public.POST("/auth/google", limit("auth"), authCtrl.GoogleLogin)
+
+	// Any OIDC issuer configured in OIDC_PROVIDERS (Apple, Microsoft, company IdP)
+	public.GET("/auth/providers", authCtrl.Providers)
+	public.POST("/auth/oidc/:provider", limit("auth"), authCtrl.OIDCLogin)
+
+	// Passwordless email: request a one-time link, then exchange its token for a session
+	public.POST("/auth/email/request", limit("auth"), authCtrl.RequestEmailLogin)
+	public.POST("/auth/email/verify", limit("auth"), authCtrl.VerifyEmailLogin)
+
+	// Rotate refresh token => new access token (web client calls this on "token_expired")
+	public.POST("/auth/refresh", limit("auth"), authCtrl.Refresh)
 
 	// Logged-in session info
-	v1.GET("/auth/me", middleware.RequireAuth(cfg.JWTSecret), authCtrl.Me)
+	authed.GET("/auth/me", authCtrl.Me)
+
+	// Logout revokes the current session and clears cookie
+	authed.POST("/auth/logout", authCtrl.Logout)
+
+	// Devices: list active sessions, revoke one, or revoke all (?except_current=true keeps this one)
+	authed.GET("/auth/sessions", authCtrl.ListSessions)
+	authed.DELETE("/auth/sessions/:id", authCtrl.RevokeSession)
+	authed.DELETE("/auth/sessions", authCtrl.RevokeAllSessions)
+
+	// Personal API keys (Authorization: Bearer tp_...) for scripts / CLI
+	authed.GET("/auth/api-keys", apiKeyCtrl.List)
+	authed.POST("/auth/api-keys", apiKeyCtrl.Create)
+	authed.DELETE("/auth/api-keys/:id", apiKeyCtrl.Revoke)
+
+	// -------- Account (privacy: export + deletion; allowed even when banned) --------
+	authed.GET("/account/export", accountCtrl.Export)
+	authed.DELETE("/account", accountCtrl.Delete)
+
+	// Travel preferences (defaults for trip requests)
+	authed.GET("/account/preferences", accountCtrl.GetPreferences)
+	authed.PUT("/account/preferences", accountCtrl.PutPreferences)
+
+	// -------- Usage --------
+	authed.GET("/usage", middleware.RejectBanned(db), usageCtrl.Summary)
+
+	// -------- Billing --------
+	authed.POST("/billing/checkout", middleware.RejectBanned(db), billingCtrl.Checkout)
+
+	authed.GET("/billing/credits", billingCtrl.Credits)
+	authed.POST("/billing/credits/checkout", middleware.RejectBanned(db), billingCtrl.BuyCredits)
+
+	// Provider webhook (signature-verified, no cookie)
+	public.POST("/billing/webhook", billingCtrl.Webhook)
 
-	// Logout clears cookie
-	v1.POST("/auth/logout", middleware.RequireAuth(cfg.JWTSecret), authCtrl.Logout)
+	// -------- Places (destination autocomplete for the trip form) --------
+	authed.GET("/places/autocomplete", middleware.RejectBanned(db), placesCtrl.Autocomplete)
 
 	// -------- Protected Trip routes --------
-	trip := v1.Group("/trip")
-	trip.Use(middleware.RequireAuth(cfg.JWTSecret))
+	trip := authed.Group("/trip")
+	trip.Use(middleware.RejectBanned(db))
 
 	trip.GET("/plans", tripCtrl.ListPlans)
 	trip.GET("/plan/:id", tripCtrl.GetPlan)
 
 	// Generate plan (2-free limit enforced inside controller)
-	trip.POST("/plan", tripCtrl.CreatePlan)
+	trip.POST("/plan", limit("plan"), tripCtrl.CreatePlan)
 
 	// Force regenerate (consumes generation)
-	trip.POST("/plan/regenerate", tripCtrl.Regenerate)
+	trip.POST("/plan/regenerate", limit("plan"), tripCtrl.Regenerate)
+
+	// Translate a saved plan's text into another language (stored on the plan)
+	trip.POST("/plan/:id/translate", limit("plan"), tripCtrl.Translate)
+
+	// -------- Admin (ADMIN_EMAILS allowlist) --------
+	admin := authed.Group("/admin")
+	admin.Use(middleware.RequireAdmin(db, cfg.AdminEmails))
+
+	admin.GET("/users", adminCtrl.ListUsers)
+	admin.GET("/users/:id", adminCtrl.GetUser)
+	admin.GET("/users/:id/plans", adminCtrl.UserPlans)
+	admin.GET("/users/:id/ledger", adminCtrl.UserLedger)
+	admin.PUT("/users/:id/quota", adminCtrl.SetQuota)
+	admin.POST("/users/:id/ban", adminCtrl.Ban)
+	admin.POST("/users/:id/unban", adminCtrl.Unban)
+	admin.POST("/users/:id/reset-usage", adminCtrl.ResetUsage)
 }
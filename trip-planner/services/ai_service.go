@@ -3,34 +3,103 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"trip-planner/models"
 	"trip-planner/utils"
 )
 
+// AIUsage is the token accounting reported by OpenAI for one generation.
+type AIUsage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	PromptVersion    string // template version that produced the prompt
+}
+
+// Cost estimates the USD cost of the generation from per-1M-token prices.
+func (u AIUsage) Cost(inputPer1M, outputPer1M float64) float64 {
+	return float64(u.PromptTokens)/1e6*inputPer1M + float64(u.CompletionTokens)/1e6*outputPer1M
+}
+
+// ErrPromptTooLarge means the request does not fit the model window even
+// with the context trimmed to the minimum.
+var ErrPromptTooLarge = errors.New("prompt exceeds token budget")
+
+// PromptConfig bounds how much places/weather context goes into a prompt.
+type PromptConfig struct {
+	Templates     *PromptTemplates
+	PlaceLimit    int // places kept per stop (before trimming)
+	ContextTokens int // model window
+	OutputReserve int // tokens kept free for the itinerary
+}
+
+// StopContext is the places + weather fetched for one stop of the route.
+type StopContext struct {
+	City    string `json:"city"`
+	Nights  int    `json:"nights,omitempty"`
+	Places  any    `json:"places"`
+	Weather any    `json:"weather"`
+
+	Details []PlaceDetails `json:"details,omitempty"` // for the top places, when fetched
+}
+
 type AIService struct {
 	apiKey string
 	model  string
+	prompt PromptConfig
 }
 
-func NewAIService(apiKey, model string) *AIService {
+func NewAIService(apiKey, model string, prompt PromptConfig) *AIService {
+	if prompt.PlaceLimit <= 0 {
+		prompt.PlaceLimit = 12
+	}
 	return &AIService{
 		apiKey: apiKey,
 		model:  model,
+		prompt: prompt,
 	}
 }
 
 func (s *AIService) GenerateTrip(
 	ctx context.Context,
 	req models.TripRequest,
-	places any,
-	weather any,
-) (map[string]any, error) {
+	country models.Country,
+	stops []StopContext,
+) (map[string]any, AIUsage, error) {
+
+	usage := AIUsage{Model: s.model}
+
+	prompt, version, err := s.fitPrompt(req, country, stops)
+	if err != nil {
+		return nil, usage, err
+	}
+	usage.PromptVersion = version
+
+	text, err := s.complete(ctx, prompt, &usage)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	// ✅ Parse guaranteed JSON
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(text), &obj); err != nil {
+		return nil, usage, fmt.Errorf("AI returned invalid JSON: %w\nRAW:\n%s", err, text)
+	}
 
-	prompt := buildPrompt(req, places, weather)
+	addPerPersonCosts(obj, req.Party.Size())
 
+	return obj, usage, nil
+}
+
+// complete sends one JSON-mode Responses API request and returns the text,
+// adding the reported token usage to usage.
+func (s *AIService) complete(ctx context.Context, prompt string, usage *AIUsage) (string, error) {
 	// ✅ CORRECT Responses API payload (2025 schema)
 	payload := map[string]any{
 		"model": s.model, // e.g. gpt-5.2
@@ -59,6 +128,11 @@ func (s *AIService) GenerateTrip(
 				Text string `json:"text"`
 			} `json:"content"`
 		} `json:"output"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
 	}
 
 	if err := utils.PostJSON(
@@ -68,7 +142,14 @@ func (s *AIService) GenerateTrip(
 		&raw,
 		headers,
 	); err != nil {
-		return nil, err
+		return "", err
+	}
+
+	usage.PromptTokens += raw.Usage.InputTokens
+	usage.CompletionTokens += raw.Usage.OutputTokens
+	usage.TotalTokens += raw.Usage.TotalTokens
+	if raw.Usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 	}
 
 	// ✅ Collect ALL text parts
@@ -82,70 +163,194 @@ func (s *AIService) GenerateTrip(
 	}
 
 	if text == "" {
-		return nil, fmt.Errorf("AI returned empty output")
+		return "", fmt.Errorf("AI returned empty output")
 	}
 
-	// ✅ Parse guaranteed JSON
-	var obj map[string]any
-	if err := json.Unmarshal([]byte(text), &obj); err != nil {
-		return nil, fmt.Errorf("AI returned invalid JSON: %w\nRAW:\n%s", err, text)
+	return text, nil
+}
+
+// addPerPersonCosts adds a per_person breakdown next to every cost range the
+// model returned (which are totals for the whole party).
+func addPerPersonCosts(itin map[string]any, size int) {
+	scale := func(v any) {
+		cr, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		per := map[string]any{}
+		for _, k := range []string{"low", "mid", "high"} {
+			if n, ok := cr[k].(float64); ok {
+				per[k] = math.Round(n / float64(size))
+			}
+		}
+		cr["party_size"] = size
+		cr["per_person"] = per
 	}
 
-	return obj, nil
+	scale(itin["total_budget"])
+	days, _ := itin["days"].([]any)
+	for _, d := range days {
+		if day, ok := d.(map[string]any); ok {
+			scale(day["cost_range"])
+		}
+	}
 }
 
-func buildPrompt(req models.TripRequest, places any, weather any) string {
+// describeParty renders the party as prompt rules.
+func describeParty(p *models.TravelParty) []string {
+	if p == nil {
+		return []string{"Party: 1 adult. Costs are for one person."}
+	}
+	var parts []string
+	if p.Adults > 0 {
+		parts = append(parts, fmt.Sprintf("%d adult(s)", p.Adults))
+	}
+	if len(p.ChildrenAges) > 0 {
+		ages := make([]string, len(p.ChildrenAges))
+		for i, a := range p.ChildrenAges {
+			ages[i] = fmt.Sprint(a)
+		}
+		parts = append(parts, fmt.Sprintf("%d child(ren) aged %s", len(p.ChildrenAges), strings.Join(ages, ", ")))
+	}
+	if p.Seniors > 0 {
+		parts = append(parts, fmt.Sprintf("%d senior(s)", p.Seniors))
+	}
+	out := []string{"Party: " + strings.Join(parts, ", ") + fmt.Sprintf(". All cost_range and total_budget figures are totals for all %d travellers (apply child/senior discounts where usual).", p.Size())}
+	if len(p.ChildrenAges) > 0 {
+		out = append(out, "Pick activities and meal stops suitable for the children's ages.")
+	}
+	if len(p.Accessibility) > 0 {
+		out = append(out, "Accessibility needs: "+strings.Join(p.Accessibility, ", ")+". Only suggest places and transport that accommodate them.")
+	}
+	return out
+}
+
+// fitPrompt builds the prompt, halving the places per stop until it fits the
+// token budget (window minus output reserve).
+func (s *AIService) fitPrompt(req models.TripRequest, country models.Country, stops []StopContext) (string, string, error) {
+	budget := s.prompt.ContextTokens - s.prompt.OutputReserve
+	limit := s.prompt.PlaceLimit
+	for {
+		prompt, version, err := s.prompt.Templates.Render(promptData(req, country, promptContext(stops, limit)))
+		if err != nil {
+			return "", "", err
+		}
+		if budget <= 0 || estimateTokens(prompt) <= budget {
+			return prompt, version, nil
+		}
+		if limit == 0 {
+			return "", "", fmt.Errorf("%w: ~%d tokens, budget %d", ErrPromptTooLarge, estimateTokens(prompt), budget)
+		}
+		limit /= 2
+	}
+}
+
+// estimateTokens is a rough count (~4 bytes per token for JSON-heavy text).
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// promptContext slims raw Google/OpenWeather responses to what the model
+// needs, labelled per stop.
+func promptContext(stops []StopContext, placeLimit int) []map[string]any {
+	out := make([]map[string]any, len(stops))
+	for i, st := range stops {
+		places := []map[string]any{}
+		if placeLimit > 0 {
+			if top, ok := SlimPlaces(st.Places, placeLimit)["top_places"].([]map[string]any); ok {
+				places = top
+			}
+		}
+		addDetails(places, st.Details)
+		ctx := map[string]any{
+			"stop":    st.City,
+			"places":  places,
+			"weather": SlimWeather(st.Weather),
+		}
+		if st.Nights > 0 {
+			ctx["nights"] = st.Nights
+		}
+		out[i] = ctx
+	}
+	return out
+}
+
+// addDetails merges the fields that matter for planning (price, closures)
+// into the slimmed places.
+func addDetails(places []map[string]any, details []PlaceDetails) {
+	byID := make(map[string]PlaceDetails, len(details))
+	for _, d := range details {
+		byID[d.PlaceID] = d
+	}
+	for _, p := range places {
+		id, _ := p["place_id"].(string)
+		d, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if d.PriceLevel != nil {
+			p["price_level"] = *d.PriceLevel
+		}
+		if d.UserRatingsTotal > 0 {
+			p["user_ratings_total"] = d.UserRatingsTotal
+		}
+		if d.BusinessStatus != "" && d.BusinessStatus != "OPERATIONAL" {
+			p["business_status"] = d.BusinessStatus
+		}
+		if len(d.ClosedOn) > 0 {
+			p["closed_on"] = d.ClosedOn
+		}
+	}
+}
+
+// PromptData is what prompt templates render against.
+type PromptData struct {
+	Request     models.TripRequest
+	Country     models.Country
+	Currency    string
+	Language    string   // e.g. "German"
+	Rules       []string // request-specific rules (party, diet, mobility, route)
+	RequestJSON string
+	ContextJSON string
+}
+
+func promptData(req models.TripRequest, country models.Country, stops []map[string]any) PromptData {
 	bReq, _ := json.MarshalIndent(req, "", "  ")
+	bCtx, _ := json.Marshal(stops)
+
+	currency := country.Currency
+	rules := describeParty(req.Party)
+	if tp := req.Traveller; tp != nil {
+		if tp.Currency != "" {
+			currency = tp.Currency
+		}
+		if len(tp.Dietary) > 0 {
+			rules = append(rules, "Meal suggestions must suit these dietary needs: "+strings.Join(tp.Dietary, ", ")+".")
+		}
+		if tp.Mobility != "" {
+			rules = append(rules, "Respect mobility constraints: "+tp.Mobility+". Avoid long hikes, steep stairs or rough access unless suitable.")
+		}
+		if tp.WithKids {
+			rules = append(rules, "Travelling with kids: keep days shorter, add rest breaks and child-friendly stops.")
+		}
+	}
 
-	return fmt.Sprintf(`
-You are a Sri Lanka trip planner. Return ONLY valid JSON (no markdown, no extra text).
-
-JSON structure:
-{
-  "summary":"string",
-  "route":["City1","City2"],
-  "total_budget":{"currency":"LKR","low":0,"mid":0,"high":0,"notes":"..."},
-  "tips":["..."],
-  "warnings":["..."],
-  "days":[
-    {
-      "day_number":1,
-      "date":"YYYY-MM-DD",
-      "base_city":"string",
-      "theme":"string",
-      "items":[
-        {
-          "time_block":"08:00-10:30",
-          "title":"...",
-          "description":"...",
-          "location":"...",
-          "travel_mode":"car",
-          "travel_mins":30
-        }
-      ],
-      "meals":[
-        {"meal_type":"breakfast","suggestion":"...","area":"..."}
-      ],
-      "hotel_area":"string",
-      "cost_range":{"currency":"LKR","low":0,"mid":0,"high":0,"notes":"..."}
-    }
-  ]
-}
-
-Rules:
-- Realistic Sri Lanka travel times.
-- Prefer sensible routes (Colombo → Kandy → Ella → Yala → Mirissa).
-- Family-safe and practical.
-- Currency must be LKR.
-- Use places context to pick REAL attractions.
-
-User request:
-%s
-
-PLACES (Google raw JSON):
-%v
-
-WEATHER (raw JSON):
-%v
-`, string(bReq), places, weather)
+	if len(req.Stops) > 1 {
+		rules = append(rules, "Follow the requested stops in order, spending the given nights at each (0 = your choice). base_city must be one of the stops.")
+	}
+
+	language := LanguageName(req.Language)
+	if language == "" {
+		language = "English"
+	}
+
+	return PromptData{
+		Request:     req,
+		Country:     country,
+		Currency:    currency,
+		Language:    language,
+		Rules:       rules,
+		RequestJSON: string(bReq),
+		ContextJSON: string(bCtx),
+	}
 }
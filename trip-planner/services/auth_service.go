@@ -2,36 +2,71 @@ package services
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"google.golang.org/api/idtoken"
 )
 
 type GoogleUser struct {
-	Sub     string
-	Email   string
-	Name    string
-	Picture string
+	Sub           string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
 }
 
 type AuthService struct {
 	GoogleClientID string
+
+	oidc map[string]*OIDCProvider
+}
+
+func NewAuthService(cid string, providers []OIDCConfig) *AuthService {
+	a := &AuthService{GoogleClientID: cid, oidc: map[string]*OIDCProvider{}}
+	for _, p := range providers {
+		a.oidc[p.Name] = NewOIDCProvider(p)
+	}
+	return a
+}
+
+// OIDCProviders lists the configured generic OIDC provider names.
+func (a *AuthService) OIDCProviders() []string {
+	out := make([]string, 0, len(a.oidc))
+	for name := range a.oidc {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
 }
 
-func NewAuthService(cid string) *AuthService {
-	return &AuthService{GoogleClientID: cid}
+func (a *AuthService) VerifyOIDCToken(ctx context.Context, provider, token, nonce string) (*OIDCIdentity, error) {
+	p, ok := a.oidc[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p.Verify(ctx, token, nonce)
 }
 
 func (a *AuthService) VerifyGoogleIDToken(ctx context.Context, token string) (*GoogleUser, error) {
 	payload, err := idtoken.Validate(ctx, token, a.GoogleClientID)
-	if err != nil { return nil, err }
+	if err != nil {
+		return nil, err
+	}
 
 	u := &GoogleUser{
-		Sub:     payload.Subject,
-		Email:   payload.Claims["email"].(string),
+		Sub:   payload.Subject,
+		Email: payload.Claims["email"].(string),
+	}
+	if v, ok := payload.Claims["name"].(string); ok {
+		u.Name = v
+	}
+	if v, ok := payload.Claims["picture"].(string); ok {
+		u.Picture = v
+	}
+	if v, ok := payload.Claims["email_verified"].(bool); ok {
+		u.EmailVerified = v
 	}
-	if v, ok := payload.Claims["name"].(string); ok { u.Name = v }
-	if v, ok := payload.Claims["picture"].(string); ok { u.Picture = v }
 	_ = time.Now()
 
 	return u, nil
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"trip-planner/utils"
+)
+
+// Normalized webhook event types
+const (
+	BillingCheckoutCompleted = "checkout.completed"
+	BillingSubscriptionEnded = "subscription.ended"
+)
+
+var ErrBadSignature = errors.New("invalid webhook signature")
+
+// Checkout modes
+const (
+	CheckoutSubscription = "subscription"
+	CheckoutPayment      = "payment" // one-off (credit packs)
+)
+
+type CheckoutRequest struct {
+	UserID string
+	Email  string
+	Mode   string // defaults to CheckoutSubscription
+	// PriceID overrides the configured subscription price; required for payments
+	PriceID string
+}
+
+type CheckoutSession struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// BillingEvent is a provider webhook reduced to what we act on.
+// Type is empty for events we ignore.
+type BillingEvent struct {
+	ID         string
+	Type       string
+	SessionID  string
+	UserID     string
+	CustomerID string
+	Paid       bool
+}
+
+type BillingProvider interface {
+	Name() string
+	CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (CheckoutSession, error)
+	// SignatureHeader is the request header carrying the webhook signature.
+	SignatureHeader() string
+	ParseWebhook(payload []byte, signature string) (BillingEvent, error)
+}
+
+type BillingConfig struct {
+	SecretKey     string
+	WebhookSecret string
+	PriceID       string
+	SuccessURL    string
+	CancelURL     string
+}
+
+// NewBillingProvider returns the provider named by BILLING_PROVIDER ("stripe" or "fake").
+func NewBillingProvider(name string, cfg BillingConfig) BillingProvider {
+	if strings.ToLower(name) == "stripe" {
+		return &StripeProvider{cfg: cfg}
+	}
+	return &FakeBillingProvider{cfg: cfg}
+}
+
+// ---------- Stripe ----------
+
+type StripeProvider struct {
+	cfg BillingConfig
+}
+
+func (s *StripeProvider) Name() string { return "stripe" }
+
+func (s *StripeProvider) SignatureHeader() string { return "Stripe-Signature" }
+
+func (s *StripeProvider) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (CheckoutSession, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = CheckoutSubscription
+	}
+	price := req.PriceID
+	if price == "" && mode == CheckoutSubscription {
+		price = s.cfg.PriceID
+	}
+	if price == "" {
+		return CheckoutSession{}, fmt.Errorf("stripe: no price for %s checkout", mode)
+	}
+
+	form := url.Values{}
+	form.Set("mode", mode)
+	form.Set("line_items[0][price]", price)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", s.cfg.SuccessURL)
+	form.Set("cancel_url", s.cfg.CancelURL)
+	form.Set("client_reference_id", req.UserID)
+	if req.Email != "" {
+		form.Set("customer_email", req.Email)
+	}
+
+	var out CheckoutSession
+	err := utils.PostForm(ctx, "https://api.stripe.com/v1/checkout/sessions", form, &out, map[string]string{
+		"Authorization": "Bearer " + s.cfg.SecretKey,
+	})
+	return out, err
+}
+
+func (s *StripeProvider) ParseWebhook(payload []byte, signature string) (BillingEvent, error) {
+	if err := VerifyWebhookSignature(s.cfg.WebhookSecret, payload, signature, 5*time.Minute, time.Now()); err != nil {
+		return BillingEvent{}, err
+	}
+	return parseStripeEvent(payload)
+}
+
+// ---------- Fake (local dev) ----------
+
+// FakeBillingProvider never calls the network. Checkout goes straight to the
+// success URL; webhooks use the Stripe payload and signature format so the
+// upgrade path can be exercised locally with a signed POST.
+type FakeBillingProvider struct {
+	cfg BillingConfig
+}
+
+func (f *FakeBillingProvider) Name() string { return "fake" }
+
+func (f *FakeBillingProvider) SignatureHeader() string { return "X-Fake-Signature" }
+
+func (f *FakeBillingProvider) CreateCheckoutSession(_ context.Context, _ CheckoutRequest) (CheckoutSession, error) {
+	id := "fake_cs_" + uuid.NewString()
+	u := f.cfg.SuccessURL
+	if strings.Contains(u, "?") {
+		u += "&session_id=" + id
+	} else {
+		u += "?session_id=" + id
+	}
+	return CheckoutSession{ID: id, URL: u}, nil
+}
+
+func (f *FakeBillingProvider) ParseWebhook(payload []byte, signature string) (BillingEvent, error) {
+	if err := VerifyWebhookSignature(f.cfg.WebhookSecret, payload, signature, 5*time.Minute, time.Now()); err != nil {
+		return BillingEvent{}, err
+	}
+	return parseStripeEvent(payload)
+}
+
+// ---------- signatures ----------
+
+// SignWebhook builds a Stripe-style signature header: "t=<unix>,v1=<hex hmac>".
+func SignWebhook(secret string, payload []byte, at time.Time) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, payload)
+}
+
+// VerifyWebhookSignature checks a "t=...,v1=..." header against payload.
+// Any v1 entry may match (providers send several during secret rotation).
+func VerifyWebhookSignature(secret string, payload []byte, header string, tolerance time.Duration, now time.Time) error {
+	if secret == "" || header == "" {
+		return ErrBadSignature
+	}
+
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrBadSignature
+	}
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrBadSignature)
+	}
+
+	expected := webhookMAC(secret, ts, payload)
+	for _, s := range sigs {
+		if hmac.Equal([]byte(s), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+func webhookMAC(secret, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func parseStripeEvent(payload []byte) (BillingEvent, error) {
+	var raw struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID                string `json:"id"`
+				ClientReferenceID string `json:"client_reference_id"`
+				Customer          string `json:"customer"`
+				PaymentStatus     string `json:"payment_status"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return BillingEvent{}, err
+	}
+
+	obj := raw.Data.Object
+	ev := BillingEvent{ID: raw.ID, CustomerID: obj.Customer}
+
+	switch raw.Type {
+	case "checkout.session.completed":
+		ev.Type = BillingCheckoutCompleted
+		ev.SessionID = obj.ID
+		ev.UserID = obj.ClientReferenceID
+		ev.Paid = obj.PaymentStatus == "paid" || obj.PaymentStatus == "no_payment_required"
+	case "customer.subscription.deleted":
+		ev.Type = BillingSubscriptionEnded
+	}
+	return ev, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed"}`)
+	now := time.Unix(1_700_000_000, 0)
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		name    string
+		secret  string
+		payload []byte
+		header  string
+		wantErr bool
+	}{
+		{"valid", secret, payload, SignWebhook(secret, payload, now), false},
+		{"valid within tolerance", secret, payload, SignWebhook(secret, payload, now.Add(-4*time.Minute)), false},
+		{"valid among rotated signatures", secret, payload, SignWebhook("whsec_old", payload, now) + ",v1=" + webhookMAC(secret, "1700000000", payload), false},
+		{"tampered payload", secret, []byte(`{"id":"evt_1","type":"checkout.session.completed","paid":true}`), SignWebhook(secret, payload, now), true},
+		{"stale timestamp", secret, payload, SignWebhook(secret, payload, now.Add(-10*time.Minute)), true},
+		{"future timestamp", secret, payload, SignWebhook(secret, payload, now.Add(10*time.Minute)), true},
+		{"wrong secret", secret, payload, SignWebhook("whsec_other", payload, now), true},
+		{"empty secret", "", payload, SignWebhook("", payload, now), true},
+		{"missing header", secret, payload, "", true},
+		{"malformed header", secret, payload, "v1=deadbeef", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.secret, tt.payload, tt.header, tolerance, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBadSignature) {
+					t.Fatalf("want ErrBadSignature, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("want nil, got %v", err)
+			}
+		})
+	}
+}
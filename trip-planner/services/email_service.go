@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+type EmailSender interface {
+	Send(ctx context.Context, msg Email) error
+}
+
+type EmailConfig struct {
+	From      string
+	SMTPHost  string
+	SMTPPort  string
+	SMTPUser  string
+	SMTPPass  string
+	OutboxDir string
+}
+
+// NewEmailSender returns the sender named by EMAIL_SENDER ("smtp" or "file").
+func NewEmailSender(kind string, cfg EmailConfig) EmailSender {
+	if strings.ToLower(kind) == "smtp" {
+		return &SMTPSender{cfg: cfg}
+	}
+	return &FileEmailSender{dir: cfg.OutboxDir, from: cfg.From}
+}
+
+// ---------- SMTP ----------
+
+type SMTPSender struct {
+	cfg EmailConfig
+}
+
+func (s *SMTPSender) Send(_ context.Context, msg Email) error {
+	var auth smtp.Auth
+	if s.cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPass, s.cfg.SMTPHost)
+	}
+	addr := s.cfg.SMTPHost + ":" + s.cfg.SMTPPort
+	return smtp.SendMail(addr, auth, s.cfg.From, []string{msg.To}, renderEmail(s.cfg.From, msg))
+}
+
+// ---------- File (local dev) ----------
+
+// FileEmailSender writes each message to OutboxDir as an .eml file instead of sending it.
+type FileEmailSender struct {
+	dir  string
+	from string
+}
+
+func (f *FileEmailSender) Send(_ context.Context, msg Email) error {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(msg.To))
+	return os.WriteFile(filepath.Join(f.dir, name), renderEmail(f.from, msg), 0644)
+}
+
+func renderEmail(from string, msg Email) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	b.WriteString(msg.Text)
+	return []byte(b.String())
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"trip-planner/models"
+)
+
+var ErrPlaceNotFound = errors.New("place not found")
+
+// PlaceSuggestion is one autocomplete result.
+type PlaceSuggestion struct {
+	PlaceID     string `json:"place_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Geocoder resolves free-text destinations to canonical places.
+type Geocoder interface {
+	Autocomplete(ctx context.Context, input string, country models.Country) ([]PlaceSuggestion, error)
+	// Resolve looks up placeID if set, otherwise geocodes query.
+	Resolve(ctx context.Context, query, placeID string, country models.Country) (models.GeoLocation, error)
+}
+
+// geocodeCacheSize caps the cache; autocomplete adds a key per keystroke.
+const geocodeCacheSize = 10000
+
+// GoogleGeocoder uses Places Autocomplete and the Geocoding API.
+type GoogleGeocoder struct {
+	apiKey string
+	ttl    time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cacheItem
+}
+
+func NewGoogleGeocoder(apiKey string, cacheHours int) *GoogleGeocoder {
+	return &GoogleGeocoder{
+		apiKey: apiKey,
+		ttl:    time.Duration(cacheHours) * time.Hour,
+		cache:  map[string]cacheItem{},
+	}
+}
+
+func (g *GoogleGeocoder) Autocomplete(ctx context.Context, input string, country models.Country) ([]PlaceSuggestion, error) {
+	input = strings.TrimSpace(input)
+	key := "autocomplete:" + strings.ToLower(country.Code+":"+input)
+	if v, ok := g.cached(key); ok {
+		return v.([]PlaceSuggestion), nil
+	}
+
+	u := "https://maps.googleapis.com/maps/api/place/autocomplete/json?input=" + url.QueryEscape(input) +
+		"&types=(regions)&components=country:" + strings.ToLower(country.Code) + "&key=" + g.apiKey
+
+	var resp struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Predictions  []struct {
+			PlaceID              string `json:"place_id"`
+			Description          string `json:"description"`
+			StructuredFormatting struct {
+				MainText string `json:"main_text"`
+			} `json:"structured_formatting"`
+		} `json:"predictions"`
+	}
+	if err := getJSONLoose(ctx, u, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "OK" && resp.Status != "ZERO_RESULTS" {
+		return nil, fmt.Errorf("autocomplete: %s %s", resp.Status, resp.ErrorMessage)
+	}
+
+	out := make([]PlaceSuggestion, 0, len(resp.Predictions))
+	for _, p := range resp.Predictions {
+		out = append(out, PlaceSuggestion{PlaceID: p.PlaceID, Name: p.StructuredFormatting.MainText, Description: p.Description})
+	}
+	g.store(key, out)
+	return out, nil
+}
+
+func (g *GoogleGeocoder) Resolve(ctx context.Context, query, placeID string, country models.Country) (models.GeoLocation, error) {
+	query = strings.TrimSpace(query)
+	params := url.Values{"key": {g.apiKey}}
+	key := "geocode:"
+	if placeID != "" {
+		params.Set("place_id", placeID)
+		key += "id:" + strings.ToLower(country.Code) + ":" + placeID
+	} else {
+		params.Set("address", query)
+		params.Set("components", "country:"+country.Code)
+		key += strings.ToLower(country.Code + ":" + query)
+	}
+	if v, ok := g.cached(key); ok {
+		loc := v.(models.GeoLocation)
+		loc.Query = query
+		return loc, nil
+	}
+
+	var resp struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Results      []struct {
+			PlaceID           string `json:"place_id"`
+			FormattedAddress  string `json:"formatted_address"`
+			AddressComponents []struct {
+				LongName  string   `json:"long_name"`
+				ShortName string   `json:"short_name"`
+				Types     []string `json:"types"`
+			} `json:"address_components"`
+			Geometry struct {
+				Location struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"location"`
+			} `json:"geometry"`
+		} `json:"results"`
+	}
+	if err := getJSONLoose(ctx, "https://maps.googleapis.com/maps/api/geocode/json?"+params.Encode(), &resp); err != nil {
+		return models.GeoLocation{}, err
+	}
+	if resp.Status == "ZERO_RESULTS" || (resp.Status == "OK" && len(resp.Results) == 0) {
+		return models.GeoLocation{}, ErrPlaceNotFound
+	}
+	if resp.Status != "OK" {
+		return models.GeoLocation{}, fmt.Errorf("geocode: %s %s", resp.Status, resp.ErrorMessage)
+	}
+
+	r := resp.Results[0]
+
+	// a client-supplied place_id isn't restricted by components=country
+	if placeID != "" {
+		inCountry := false
+		for _, c := range r.AddressComponents {
+			if hasType(c.Types, "country") && strings.EqualFold(c.ShortName, country.Code) {
+				inCountry = true
+				break
+			}
+		}
+		if !inCountry {
+			return models.GeoLocation{}, ErrPlaceNotFound
+		}
+	}
+
+	loc := models.GeoLocation{
+		PlaceID: r.PlaceID,
+		Address: r.FormattedAddress,
+		Lat:     r.Geometry.Location.Lat,
+		Lng:     r.Geometry.Location.Lng,
+	}
+	// canonical name: most specific named area, else first address segment
+	for _, c := range r.AddressComponents {
+		if hasType(c.Types, "locality", "natural_feature", "colloquial_area", "administrative_area_level_2") {
+			loc.Name = c.LongName
+			break
+		}
+	}
+	if loc.Name == "" {
+		loc.Name, _, _ = strings.Cut(r.FormattedAddress, ",")
+	}
+
+	g.store(key, loc)
+	loc.Query = query
+	return loc, nil
+}
+
+func (g *GoogleGeocoder) cached(key string) (any, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if item, ok := g.cache[key]; ok && time.Now().Before(item.expires) {
+		return item.value, true
+	}
+	return nil, false
+}
+
+func (g *GoogleGeocoder) store(key string, v any) {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.cache) >= geocodeCacheSize {
+		g.evict(now)
+	}
+	g.cache[key] = cacheItem{expires: now.Add(g.ttl), value: v}
+}
+
+// evict drops expired entries, then arbitrary ones until the cache is back
+// under 90% of its cap (caller holds mu).
+func (g *GoogleGeocoder) evict(now time.Time) {
+	for k, item := range g.cache {
+		if !now.Before(item.expires) {
+			delete(g.cache, k)
+		}
+	}
+	for k := range g.cache {
+		if len(g.cache) < geocodeCacheSize*9/10 {
+			break
+		}
+		delete(g.cache, k)
+	}
+}
+
+func hasType(types []string, want ...string) bool {
+	for _, t := range types {
+		for _, w := range want {
+			if t == w {
+				return true
+			}
+		}
+	}
+	return false
+}
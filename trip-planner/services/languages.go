@@ -0,0 +1,25 @@
+package services
+
+// languageNames maps the ISO 639-1 codes we may generate in to the name used
+// in prompts. Which ones are enabled is SUPPORTED_LANGUAGES.
+var languageNames = map[string]string{
+	"en": "English",
+	"si": "Sinhala",
+	"ta": "Tamil",
+	"hi": "Hindi",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"it": "Italian",
+	"nl": "Dutch",
+	"ru": "Russian",
+	"zh": "Simplified Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"th": "Thai",
+}
+
+// LanguageName returns the prompt name for a language code ("" if unknown).
+func LanguageName(code string) string {
+	return languageNames[code]
+}
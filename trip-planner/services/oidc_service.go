@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"trip-planner/utils"
+)
+
+var ErrUnknownProvider = errors.New("unknown login provider")
+
+// OIDCConfig registers one OpenID Connect issuer.
+type OIDCConfig struct {
+	Name     string // path segment, e.g. "apple", "microsoft", "acme"
+	Issuer   string
+	ClientID string
+}
+
+// OIDCIdentity is the verified subset of ID token claims we use.
+type OIDCIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+const (
+	jwksTTL        = time.Hour
+	jwksMinRefresh = time.Minute // unknown kid refreshes are throttled to this
+)
+
+// OIDCProvider verifies ID tokens for one issuer using its discovery
+// document and a cached JWKS.
+type OIDCProvider struct {
+	cfg OIDCConfig
+
+	mu        sync.Mutex
+	issuer    string // from discovery (authoritative)
+	jwksURI   string
+	keys      map[string]any // kid -> *rsa.PublicKey | *ecdsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{cfg: cfg}
+}
+
+func (p *OIDCProvider) Name() string { return p.cfg.Name }
+
+// Verify checks signature, issuer, audience and expiry. nonce is checked when non-empty.
+func (p *OIDCProvider) Verify(ctx context.Context, rawToken, nonce string) (*OIDCIdentity, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if nonce != "" {
+		if v, _ := claims["nonce"].(string); v != nonce {
+			return nil, errors.New("nonce mismatch")
+		}
+	}
+
+	id := &OIDCIdentity{Provider: p.cfg.Name}
+	id.Subject, _ = claims["sub"].(string)
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	id.Picture, _ = claims["picture"].(string)
+	// some issuers (Apple) send email_verified as the string "true"
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		id.EmailVerified = v
+	case string:
+		id.EmailVerified = v == "true"
+	}
+
+	if id.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return id, nil
+}
+
+func (p *OIDCProvider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.jwksURI != "" {
+		return nil
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	u := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSONLoose(ctx, u, &doc); err != nil {
+		return fmt.Errorf("oidc discovery for %s: %w", p.cfg.Name, err)
+	}
+	if doc.JWKSURI == "" {
+		return fmt.Errorf("oidc discovery for %s: missing jwks_uri", p.cfg.Name)
+	}
+	// OIDC Discovery 4.3: the document must be for the issuer we configured
+	if strings.TrimRight(doc.Issuer, "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return fmt.Errorf("oidc discovery for %s: issuer %q does not match %q", p.cfg.Name, doc.Issuer, p.cfg.Issuer)
+	}
+	p.issuer = doc.Issuer
+	p.jwksURI = doc.JWKSURI
+	return nil
+}
+
+// key returns the verification key for kid, refreshing the JWKS when it is
+// stale or the kid is unknown (issuer rotated keys).
+func (p *OIDCProvider) key(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.keys[kid]; ok && time.Since(p.fetchedAt) < jwksTTL {
+		return k, nil
+	}
+	if time.Since(p.fetchedAt) >= jwksMinRefresh {
+		if err := p.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("no key %q for %s", kid, p.cfg.Name)
+}
+
+func (p *OIDCProvider) fetchKeys(ctx context.Context) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := getJSONLoose(ctx, p.jwksURI, &set); err != nil {
+		return fmt.Errorf("jwks for %s: %w", p.cfg.Name, err)
+	}
+
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := b64Int(k.N)
+			e, err2 := b64Int(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err1 := b64Int(k.X)
+			y, err2 := b64Int(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+func b64Int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// getJSONLoose decodes into a struct while tolerating unknown fields
+// (utils.GetJSON rejects them for struct targets).
+func getJSONLoose(ctx context.Context, url string, out any) error {
+	var raw json.RawMessage
+	if err := utils.GetJSON(ctx, url, &raw, nil); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PlaceDetails is the subset of a Google Place Details response we use.
+type PlaceDetails struct {
+	PlaceID          string   `json:"place_id"`
+	Name             string   `json:"name"`
+	BusinessStatus   string   `json:"business_status,omitempty"` // OPERATIONAL | CLOSED_TEMPORARILY | CLOSED_PERMANENTLY
+	Rating           float64  `json:"rating,omitempty"`
+	UserRatingsTotal int      `json:"user_ratings_total,omitempty"`
+	PriceLevel       *int     `json:"price_level,omitempty"` // 0 (free) .. 4 (very expensive)
+	Website          string   `json:"website,omitempty"`
+	Phone            string   `json:"phone,omitempty"`
+	MapsURL          string   `json:"maps_url,omitempty"`
+	PhotoRefs        []string `json:"photo_refs,omitempty"`
+
+	OpeningHours *OpeningHours `json:"opening_hours,omitempty"`
+
+	// Trip dates (YYYY-MM-DD) the place is closed on; filled per request
+	ClosedOn []string `json:"closed_on,omitempty"`
+}
+
+type OpeningHours struct {
+	WeekdayText []string        `json:"weekday_text"`
+	Periods     []OpeningPeriod `json:"periods"`
+}
+
+// OpeningPeriod days are 0=Sunday..6=Saturday, times "HHMM" local.
+type OpeningPeriod struct {
+	Open struct {
+		Day  int    `json:"day"`
+		Time string `json:"time"`
+	} `json:"open"`
+	Close *struct {
+		Day  int    `json:"day"`
+		Time string `json:"time"`
+	} `json:"close,omitempty"`
+}
+
+const placeDetailsFields = "place_id,name,business_status,rating,user_ratings_total,price_level," +
+	"website,international_phone_number,url,photos,opening_hours"
+
+// GetPlaceDetails returns details for one place (cached separately from searches).
+func (s *PlacesService) GetPlaceDetails(ctx context.Context, placeID string) (PlaceDetails, error) {
+	key := "details:" + placeID
+
+	s.mu.RLock()
+	if item, ok := s.detailsCache[key]; ok && time.Now().Before(item.expires) {
+		s.mu.RUnlock()
+		return item.value.(PlaceDetails), nil
+	}
+	s.mu.RUnlock()
+
+	u := "https://maps.googleapis.com/maps/api/place/details/json?place_id=" + url.QueryEscape(placeID) +
+		"&fields=" + placeDetailsFields + "&key=" + s.apiKey
+
+	var resp struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Result       struct {
+			PlaceID          string        `json:"place_id"`
+			Name             string        `json:"name"`
+			BusinessStatus   string        `json:"business_status"`
+			Rating           float64       `json:"rating"`
+			UserRatingsTotal int           `json:"user_ratings_total"`
+			PriceLevel       *int          `json:"price_level"`
+			Website          string        `json:"website"`
+			Phone            string        `json:"international_phone_number"`
+			URL              string        `json:"url"`
+			OpeningHours     *OpeningHours `json:"opening_hours"`
+			Photos           []struct {
+				PhotoReference string `json:"photo_reference"`
+			} `json:"photos"`
+		} `json:"result"`
+	}
+	if err := getJSONLoose(ctx, u, &resp); err != nil {
+		return PlaceDetails{}, err
+	}
+	if resp.Status != "OK" {
+		return PlaceDetails{}, fmt.Errorf("place details %s: %s %s", placeID, resp.Status, resp.ErrorMessage)
+	}
+
+	r := resp.Result
+	d := PlaceDetails{
+		PlaceID:          r.PlaceID,
+		Name:             r.Name,
+		BusinessStatus:   r.BusinessStatus,
+		Rating:           r.Rating,
+		UserRatingsTotal: r.UserRatingsTotal,
+		PriceLevel:       r.PriceLevel,
+		Website:          r.Website,
+		Phone:            r.Phone,
+		MapsURL:          r.URL,
+		OpeningHours:     r.OpeningHours,
+	}
+	for i, p := range r.Photos {
+		if i == 3 {
+			break
+		}
+		d.PhotoRefs = append(d.PhotoRefs, p.PhotoReference)
+	}
+
+	s.mu.Lock()
+	s.detailsCache[key] = cacheItem{expires: time.Now().Add(s.detailsTTL), value: d}
+	s.mu.Unlock()
+
+	return d, nil
+}
+
+// ClosedDates returns the dates on which the place is closed: every date if
+// it has shut down, otherwise dates whose weekday has no opening period.
+// Unknown hours count as open.
+func (d PlaceDetails) ClosedDates(dates []time.Time) []string {
+	out := []string{}
+	shut := d.BusinessStatus == "CLOSED_TEMPORARILY" || d.BusinessStatus == "CLOSED_PERMANENTLY"
+	for _, day := range dates {
+		if shut || (d.OpeningHours != nil && !d.OpeningHours.opensOn(day.Weekday())) {
+			out = append(out, day.Format("2006-01-02"))
+		}
+	}
+	return out
+}
+
+func (h *OpeningHours) opensOn(wd time.Weekday) bool {
+	if len(h.Periods) == 0 {
+		return true // hours published as text only
+	}
+	for _, p := range h.Periods {
+		if p.Open.Day == int(wd) || (p.Close == nil && p.Open.Time == "0000") { // no close = open 24/7
+			return true
+		}
+	}
+	return false
+}
+
+// EnrichItinerary attaches place details to itinerary items, matched on the
+// place_id the model copies from the context (or, failing that, on the place
+// name), and flags items scheduled on a closed date. Closures are recorded as
+// data in "closures" so the client can word them in the plan's language.
+func EnrichItinerary(itin map[string]any, details []PlaceDetails) {
+	if len(details) == 0 {
+		return
+	}
+
+	var closures []any
+	days, _ := itin["days"].([]any)
+	for _, dv := range days {
+		day, ok := dv.(map[string]any)
+		if !ok {
+			continue
+		}
+		date, _ := day["date"].(string)
+		items, _ := day["items"].([]any)
+		for _, iv := range items {
+			item, ok := iv.(map[string]any)
+			if !ok {
+				continue
+			}
+			placeID, _ := item["place_id"].(string)
+			title, _ := item["title"].(string)
+			location, _ := item["location"].(string)
+			d, ok := matchPlace(details, placeID, title, location)
+			if !ok {
+				continue
+			}
+
+			item["place_id"] = d.PlaceID
+			item["place"] = placeSummary(d)
+			for _, closed := range d.ClosedOn {
+				if closed == date {
+					item["closed"] = true
+					closures = append(closures, map[string]any{
+						"place_id":   d.PlaceID,
+						"name":       d.Name,
+						"date":       date,
+						"day_number": day["day_number"],
+						"status":     d.BusinessStatus,
+					})
+					break
+				}
+			}
+		}
+	}
+
+	if closures != nil {
+		itin["closures"] = closures
+	}
+}
+
+// placeSummary is what the frontend needs for an item card.
+func placeSummary(d PlaceDetails) map[string]any {
+	out := map[string]any{
+		"name":               d.Name,
+		"rating":             d.Rating,
+		"user_ratings_total": d.UserRatingsTotal,
+	}
+	if d.PriceLevel != nil {
+		out["price_level"] = *d.PriceLevel
+	}
+	if d.Website != "" {
+		out["website"] = d.Website
+	}
+	if d.Phone != "" {
+		out["phone"] = d.Phone
+	}
+	if d.MapsURL != "" {
+		out["maps_url"] = d.MapsURL
+	}
+	if len(d.PhotoRefs) > 0 {
+		out["photo_refs"] = d.PhotoRefs
+	}
+	if d.OpeningHours != nil && len(d.OpeningHours.WeekdayText) > 0 {
+		out["opening_hours"] = d.OpeningHours.WeekdayText
+	}
+	return out
+}
+
+// matchPlace finds the place with the item's place_id, else the place whose
+// name appears in the item title or location, preferring the longest (most
+// specific) name. Names only match when the plan is in the places' language.
+func matchPlace(details []PlaceDetails, placeID, title, location string) (PlaceDetails, bool) {
+	if placeID != "" {
+		for _, d := range details {
+			if d.PlaceID == placeID {
+				return d, true
+			}
+		}
+	}
+
+	title, location = strings.ToLower(title), strings.ToLower(location)
+	best, found := PlaceDetails{}, false
+	for _, d := range details {
+		name := strings.ToLower(strings.TrimSpace(d.Name))
+		if len(name) < 4 {
+			continue
+		}
+		hit := strings.Contains(title, name) || strings.Contains(location, name)
+		if hit && (!found || len(name) > len(best.Name)) {
+			best, found = d, true
+		}
+	}
+	return best, found
+}
@@ -2,20 +2,25 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"trip-planner/models"
 	"trip-planner/utils"
 )
 
 type PlacesService struct {
-	apiKey string
-	ttl    time.Duration
+	apiKey     string
+	ttl        time.Duration
+	detailsTTL time.Duration
 
-	mu    sync.RWMutex
-	cache map[string]cacheItem
+	mu           sync.RWMutex
+	cache        map[string]cacheItem
+	detailsCache map[string]cacheItem // place details (hours change less often than rankings)
 }
 
 type cacheItem struct {
@@ -23,17 +28,126 @@ type cacheItem struct {
 	value   any // cached RAW Google response
 }
 
-func NewPlacesService(apiKey string, cacheHours int) *PlacesService {
+func NewPlacesService(apiKey string, cacheHours, detailsCacheHours int) *PlacesService {
 	return &PlacesService{
-		apiKey: apiKey,
-		ttl:    time.Duration(cacheHours) * time.Hour,
-		cache:  map[string]cacheItem{},
+		apiKey:       apiKey,
+		ttl:          time.Duration(cacheHours) * time.Hour,
+		detailsTTL:   time.Duration(detailsCacheHours) * time.Hour,
+		cache:        map[string]cacheItem{},
+		detailsCache: map[string]cacheItem{},
 	}
 }
 
-// GetPlacesByCity returns RAW Google response (cached)
-func (s *PlacesService) GetPlacesByCity(ctx context.Context, city string) (any, error) {
-	key := "places:" + strings.ToLower(strings.TrimSpace(city))
+// interestQueries maps common TripRequest interests to search terms. Only
+// these get their own search; other free-text interests still reach the AI
+// through the request but don't fan out into Places queries.
+var interestQueries = map[string]string{
+	"beach":    "beaches",
+	"beaches":  "beaches",
+	"hiking":   "hiking trails",
+	"temples":  "temples",
+	"culture":  "cultural sites and museums",
+	"history":  "historical sites",
+	"wildlife": "wildlife safaris and national parks",
+	"nature":   "nature spots and waterfalls",
+	"food":     "local restaurants",
+	"surfing":  "surf spots",
+	"shopping": "markets and shopping",
+	"tea":      "tea plantations",
+}
+
+const maxInterestSearches = 5
+
+// GetPlacesByCity returns a RAW Google text-search shaped response: top
+// attractions plus one search per interest, merged round-robin and
+// de-duplicated by place_id. Each query is cached on its own.
+func (s *PlacesService) GetPlacesByCity(ctx context.Context, city string, country models.Country, interests []string) (any, error) {
+	type search struct {
+		interest string
+		query    string
+	}
+	searches := []search{{interest: "top", query: "top attractions"}}
+	seen := map[string]bool{}
+	for _, in := range interests {
+		in = strings.ToLower(strings.TrimSpace(in))
+		q, ok := interestQueries[in]
+		if !ok || seen[q] || len(searches) > maxInterestSearches {
+			continue
+		}
+		seen[q] = true
+		searches = append(searches, search{interest: in, query: q})
+	}
+
+	results := make([][]any, len(searches))
+	errs := make([]error, len(searches))
+	var wg sync.WaitGroup
+	for i, sr := range searches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			raw, err := s.textSearch(ctx, sr.query+" in "+strings.TrimSpace(city)+" "+country.Name, country)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			m, _ := raw.(map[string]any)
+			results[i], _ = m["results"].([]any)
+		}()
+	}
+	wg.Wait()
+
+	// attractions are the baseline; a failed interest search just narrows the context
+	if errs[0] != nil {
+		return nil, errs[0]
+	}
+	for i, err := range errs[1:] {
+		if err != nil {
+			log.Printf("places search %q: %v", searches[i+1].query, err)
+		}
+	}
+
+	// round-robin so the first N (SlimPlaces limit) cover every interest
+	merged := []any{}
+	byID := map[string]map[string]any{}
+	for round := 0; ; round++ {
+		added := false
+		for i, res := range results {
+			if round >= len(res) {
+				continue
+			}
+			added = true
+			r, ok := res[round].(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := r["place_id"].(string)
+			if prev, ok := byID[id]; ok && id != "" {
+				prev["interests"] = append(prev["interests"].([]string), searches[i].interest)
+				continue
+			}
+			place := make(map[string]any, len(r)+1) // copy: r is shared with the cache
+			for k, v := range r {
+				place[k] = v
+			}
+			place["interests"] = []string{searches[i].interest}
+			byID[id] = place
+			merged = append(merged, place)
+		}
+		if !added {
+			break
+		}
+	}
+
+	status := "OK"
+	if len(merged) == 0 {
+		status = "ZERO_RESULTS"
+	}
+	return map[string]any{"status": status, "results": merged}, nil
+}
+
+// textSearch runs one Places text search (cached per query).
+func (s *PlacesService) textSearch(ctx context.Context, query string, country models.Country) (any, error) {
+	key := "places:" + strings.ToLower(country.Code+":"+query)
 
 	s.mu.RLock()
 	if item, ok := s.cache[key]; ok && time.Now().Before(item.expires) {
@@ -42,13 +156,20 @@ func (s *PlacesService) GetPlacesByCity(ctx context.Context, city string) (any,
 	}
 	s.mu.RUnlock()
 
-	q := url.QueryEscape("top attractions in " + city + " Sri Lanka")
-	u := "https://maps.googleapis.com/maps/api/place/textsearch/json?query=" + q + "&key=" + s.apiKey
+	q := url.QueryEscape(query)
+	u := "https://maps.googleapis.com/maps/api/place/textsearch/json?query=" + q +
+		"&region=" + strings.ToLower(country.Code) + "&key=" + s.apiKey
 
 	var resp any
 	if err := utils.GetJSON(ctx, u, &resp, nil); err != nil {
 		return nil, err
 	}
+	// Google reports quota/key problems as 200 with an error status; don't cache those
+	m, _ := resp.(map[string]any)
+	if status, _ := m["status"].(string); status != "OK" && status != "ZERO_RESULTS" {
+		msg, _ := m["error_message"].(string)
+		return nil, fmt.Errorf("places search: %s %s", status, msg)
+	}
 
 	s.mu.Lock()
 	s.cache[key] = cacheItem{expires: time.Now().Add(s.ttl), value: resp}
@@ -96,6 +217,9 @@ func SlimPlaces(raw any, limit int) map[string]any {
 			"types":    r["types"],
 			"place_id": r["place_id"],
 		}
+		if v, ok := r["interests"]; ok {
+			place["interests"] = v // which searches found it
+		}
 
 		// keep one address field if available
 		if v, ok := r["formatted_address"]; ok {
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"text/template"
+	"time"
+)
+
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// A template may declare its version on the first line:
+//
+//	{{/* version: itinerary-v2 */}}
+//
+// The version always ends in a short hash of the file ("itinerary-v2+1a2b3c4d5e6f",
+// or "itinerary-1a2b3c4d5e6f" undeclared), so an edit that forgets to bump
+// the declared name is still a new version.
+var versionRe = regexp.MustCompile(`^\{\{/\*\s*version:\s*(\S+)\s*\*/\}\}`)
+
+// PromptTemplates renders a named text/template prompt. Templates come from
+// dir (reloaded when the file changes) or, if dir is empty, the built-in copy.
+type PromptTemplates struct {
+	dir  string
+	name string
+
+	mu      sync.RWMutex
+	tmpl    *template.Template
+	version string
+	modTime time.Time
+}
+
+func NewPromptTemplates(dir, name string) (*PromptTemplates, error) {
+	p := &PromptTemplates{dir: dir, name: name}
+	if dir == "" {
+		src, err := builtinPrompts.ReadFile("prompts/" + name + ".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("built-in prompt %q: %w", name, err)
+		}
+		return p, p.parse(src, time.Time{})
+	}
+
+	info, err := os.Stat(p.path())
+	if err != nil {
+		return nil, err
+	}
+	src, err := os.ReadFile(p.path())
+	if err != nil {
+		return nil, err
+	}
+	return p, p.parse(src, info.ModTime())
+}
+
+// Render executes the current template and returns the prompt and its version.
+func (p *PromptTemplates) Render(data PromptData) (string, string, error) {
+	p.reload()
+
+	p.mu.RLock()
+	tmpl, version := p.tmpl, p.version
+	p.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", version, fmt.Errorf("render prompt %s: %w", version, err)
+	}
+	return buf.String(), version, nil
+}
+
+// Version is the version of the template currently in use.
+func (p *PromptTemplates) Version() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+func (p *PromptTemplates) path() string {
+	return filepath.Join(p.dir, p.name+".tmpl")
+}
+
+// reload re-parses the file if its mtime changed. A broken edit keeps the
+// last good template.
+func (p *PromptTemplates) reload() {
+	if p.dir == "" {
+		return
+	}
+	info, err := os.Stat(p.path())
+	if err != nil {
+		return
+	}
+	p.mu.RLock()
+	unchanged := info.ModTime().Equal(p.modTime)
+	p.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	src, err := os.ReadFile(p.path())
+	if err == nil {
+		err = p.parse(src, info.ModTime())
+	}
+	if err != nil {
+		log.Printf("prompt reload %s: %v (keeping %s)", p.path(), err, p.Version())
+		p.mu.Lock()
+		p.modTime = info.ModTime() // don't retry until the next edit
+		p.mu.Unlock()
+		return
+	}
+	log.Printf("prompt %s reloaded: version %s", p.name, p.Version())
+}
+
+func (p *PromptTemplates) parse(src []byte, modTime time.Time) error {
+	tmpl, err := template.New(p.name).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(src)
+	version := p.name + "-" + hex.EncodeToString(sum[:6])
+	if m := versionRe.FindSubmatch(src); m != nil {
+		version = string(m[1]) + "+" + hex.EncodeToString(sum[:6])
+	}
+
+	p.mu.Lock()
+	p.tmpl, p.version, p.modTime = tmpl, version, modTime
+	p.mu.Unlock()
+	return nil
+}
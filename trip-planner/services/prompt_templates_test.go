@@ -0,0 +1,106 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"trip-planner/models"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden")
+
+// TestItineraryPromptGolden renders fixed requests through the built-in
+// template. A diff here is a prompt change: review it, then run
+// go test ./services -run Golden -update.
+func TestItineraryPromptGolden(t *testing.T) {
+	tmpl, err := NewPromptTemplates("", "itinerary")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lk := models.Country{Code: "LK", Name: "Sri Lanka", Currency: "LKR", Timezone: "Asia/Colombo", Locale: "en-LK"}
+	stops := []map[string]any{{
+		"city":    "Kandy",
+		"weather": map[string]any{"summary": "Warm, afternoon showers"},
+		"places":  []map[string]any{{"name": "Temple of the Tooth", "place_id": "ChIJ-tooth", "rating": 4.7}},
+	}}
+
+	tests := []struct {
+		golden string
+		req    models.TripRequest
+	}{
+		{"itinerary_basic", models.TripRequest{
+			Destination: "Kandy",
+			Country:     "LK",
+			StartDate:   "2026-03-02",
+			Days:        3,
+			Budget:      "mid",
+			Interests:   []string{"culture", "food"},
+			Pace:        "balanced",
+			Language:    "en",
+		}},
+		{"itinerary_party", models.TripRequest{
+			Country:   "LK",
+			Days:      5,
+			Budget:    "low",
+			Pace:      "chill",
+			Language:  "de",
+			Stops:     []models.TripStop{{City: "Kandy", Nights: 2}, {City: "Ella", Nights: 0}},
+			Party:     &models.TravelParty{Adults: 2, ChildrenAges: []int{4}, Seniors: 1},
+			Traveller: &models.TravellerProfile{Currency: "EUR", Dietary: []string{"vegetarian"}, Mobility: "no stairs"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			prompt, _, err := tmpl.Render(promptData(tt.req, lk, stops))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", tt.golden+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(prompt), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if prompt != string(want) {
+				t.Errorf("prompt differs from %s; rerun with -update if intended\n--- got ---\n%s", path, prompt)
+			}
+		})
+	}
+}
+
+func TestPromptVersionIncludesHash(t *testing.T) {
+	tmpl, err := NewPromptTemplates("", "itinerary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := tmpl.Version(); !regexp.MustCompile(`^itinerary-v\d+\+[0-9a-f]{12}$`).MatchString(v) {
+		t.Fatalf("version %q: want declared name plus content hash", v)
+	}
+
+	dir := t.TempDir()
+	write := func(src string) string {
+		if err := os.WriteFile(filepath.Join(dir, "x.tmpl"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		p, err := NewPromptTemplates(dir, "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Version()
+	}
+	a := write("{{/* version: x-v1 */}}\nPlan {{.Country.Name}}.")
+	b := write("{{/* version: x-v1 */}}\nPlan a trip to {{.Country.Name}}.")
+	if a == b {
+		t.Fatalf("edited template kept version %q", a)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// translatableFields are the user-facing text fields of an itinerary, as
+// dotted paths where * matches every array element. Everything else (numbers,
+// times, place names, IDs) is left untouched.
+var translatableFields = []string{
+	"summary",
+	"tips.*",
+	"warnings.*",
+	"total_budget.notes",
+	"days.*.theme",
+	"days.*.cost_range.notes",
+	"days.*.items.*.title",
+	"days.*.items.*.description",
+	"days.*.meals.*.suggestion",
+}
+
+// TranslatePlan returns a copy of itinerary with its text fields translated
+// into language (ISO 639-1). Only the text is sent to the model, keyed by
+// path, so structure and numbers cannot drift.
+func (s *AIService) TranslatePlan(ctx context.Context, itinerary any, language string) (map[string]any, AIUsage, error) {
+	usage := AIUsage{Model: s.model}
+
+	name := LanguageName(language)
+	if name == "" {
+		return nil, usage, fmt.Errorf("unknown language %q", language)
+	}
+
+	// deep copy without the shared places/weather context
+	var out map[string]any
+	b, _ := json.Marshal(itinerary)
+	if err := json.Unmarshal(b, &out); err != nil || out == nil {
+		return nil, usage, fmt.Errorf("plan has no itinerary to translate")
+	}
+	delete(out, "weather")
+	delete(out, "places")
+	delete(out, "stop_context")
+
+	texts := map[string]string{}
+	for _, f := range translatableFields {
+		eachText(out, strings.Split(f, "."), "", func(path, text string) string {
+			texts[path] = text
+			return text
+		})
+	}
+	if len(texts) == 0 {
+		return out, usage, nil
+	}
+
+	bTexts, _ := json.Marshal(texts)
+	prompt := fmt.Sprintf(`
+Translate the values of this JSON object into %s. Return ONLY a JSON object with exactly the same keys (no markdown, no extra text).
+Keep place names, numbers, times, dates and currency codes as they are. Keep the tone short and practical.
+
+%s
+`, name, string(bTexts))
+
+	text, err := s.complete(ctx, prompt, &usage)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	var translated map[string]string
+	if err := json.Unmarshal([]byte(text), &translated); err != nil {
+		return nil, usage, fmt.Errorf("AI returned invalid JSON: %w\nRAW:\n%s", err, text)
+	}
+
+	for _, f := range translatableFields {
+		eachText(out, strings.Split(f, "."), "", func(path, text string) string {
+			if t, ok := translated[path]; ok && strings.TrimSpace(t) != "" {
+				return t
+			}
+			return text
+		})
+	}
+	return out, usage, nil
+}
+
+// eachText calls fn on every non-empty string matching pattern under node and
+// stores what fn returns in its place.
+func eachText(node any, pattern []string, path string, fn func(path, text string) string) any {
+	if len(pattern) == 0 {
+		if s, ok := node.(string); ok && s != "" {
+			return fn(path, s)
+		}
+		return node
+	}
+
+	key, rest := pattern[0], pattern[1:]
+	switch v := node.(type) {
+	case map[string]any:
+		if child, ok := v[key]; ok && key != "*" {
+			v[key] = eachText(child, rest, joinPath(path, key), fn)
+		}
+	case []any:
+		if key == "*" {
+			for i := range v {
+				v[i] = eachText(v[i], rest, joinPath(path, strconv.Itoa(i)), fn)
+			}
+		}
+	}
+	return node
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
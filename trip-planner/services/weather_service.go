@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"trip-planner/models"
 	"trip-planner/utils"
 )
 
@@ -27,13 +28,13 @@ func NewWeatherService(apiKey string, cacheHours int) *WeatherService {
 }
 
 // GetCityWeather returns RAW OpenWeather response (cached)
-func (s *WeatherService) GetCityWeather(ctx context.Context, city string) (any, error) {
+func (s *WeatherService) GetCityWeather(ctx context.Context, city string, country models.Country) (any, error) {
 	if strings.TrimSpace(s.apiKey) == "" {
 		// No key => disabled
 		return map[string]any{"enabled": false}, nil
 	}
 
-	key := "weather:" + strings.ToLower(strings.TrimSpace(city))
+	key := "weather:" + strings.ToLower(country.Code+":"+strings.TrimSpace(city))
 
 	s.mu.RLock()
 	if item, ok := s.cache[key]; ok && time.Now().Before(item.expires) {
@@ -42,7 +43,7 @@ func (s *WeatherService) GetCityWeather(ctx context.Context, city string) (any,
 	}
 	s.mu.RUnlock()
 
-	q := url.QueryEscape(strings.TrimSpace(city) + ",LK")
+	q := url.QueryEscape(strings.TrimSpace(city) + "," + country.Code)
 	u := "https://api.openweathermap.org/data/2.5/weather?q=" + q + "&appid=" + s.apiKey + "&units=metric"
 
 	var resp any
@@ -86,11 +87,11 @@ func SlimWeather(raw any) map[string]any {
 	}
 
 	return map[string]any{
-		"enabled":       true,
-		"temp_c":        main["temp"],
-		"feels_like_c":  main["feels_like"],
-		"humidity":      main["humidity"],
-		"condition":     condition,
-		"wind_mps":      wind["speed"],
+		"enabled":      true,
+		"temp_c":       main["temp"],
+		"feels_like_c": main["feels_like"],
+		"humidity":     main["humidity"],
+		"condition":    condition,
+		"wind_mps":     wind["speed"],
 	}
 }
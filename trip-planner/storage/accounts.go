@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"trip-planner/models"
+	"trip-planner/utils"
+)
+
+// RequestAccountDeletion schedules the user for purge after deleteAfter.
+func RequestAccountDeletion(db *sql.DB, userID string, deleteAfter time.Time) error {
+	return execOne(db, `UPDATE users SET deletion_requested_at = ?, delete_after = ? WHERE id = ?`,
+		time.Now().Unix(), deleteAfter.Unix(), userID)
+}
+
+// CancelAccountDeletion clears a pending deletion. It reports whether one was pending.
+func CancelAccountDeletion(db *sql.DB, userID string) (bool, error) {
+	res, err := db.Exec(`UPDATE users SET deletion_requested_at = NULL, delete_after = NULL
+		WHERE id = ? AND delete_after IS NOT NULL`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func RevokeUserAPIKeys(db *sql.DB, userID string) error {
+	_, err := db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`,
+		time.Now().Unix(), userID)
+	return err
+}
+
+// PurgeUser deletes every row we hold about a user.
+func PurgeUser(db *sql.DB, userID string) error {
+	email, _ := GetUserEmail(db, userID)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		`DELETE FROM usage WHERE user_id = ?`,
+		`DELETE FROM usage_ledger WHERE user_id = ?`,
+		`DELETE FROM sessions WHERE user_id = ?`,
+		`DELETE FROM refresh_tokens WHERE user_id = ?`,
+		`DELETE FROM api_keys WHERE user_id = ?`,
+		`DELETE FROM user_identities WHERE user_id = ?`,
+		`DELETE FROM credit_purchases WHERE user_id = ?`,
+		`DELETE FROM checkout_sessions WHERE user_id = ?`,
+		`DELETE FROM user_preferences WHERE user_id = ?`,
+		`DELETE FROM users WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(stmt, userID); err != nil {
+			return err
+		}
+	}
+	if email != "" {
+		if _, err := tx.Exec(`DELETE FROM login_tokens WHERE lower(email) = lower(?)`, email); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// PurgeDueAccounts removes accounts whose grace period has ended, including
+// their plans in the JSON store.
+func PurgeDueAccounts(db *sql.DB, plans *utils.JSONStore[models.TripPlan]) error {
+	rows, err := db.Query(`SELECT id FROM users WHERE delete_after IS NOT NULL AND delete_after <= ?`, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	var due []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if len(due) == 0 {
+		return nil
+	}
+
+	drop := map[string]bool{}
+	for _, id := range due {
+		drop[id] = true
+	}
+
+	// plans first: if this fails the DB rows stay and we retry next run
+	err = plans.Update(func(all []models.TripPlan) []models.TripPlan {
+		kept := make([]models.TripPlan, 0, len(all))
+		for _, p := range all {
+			if !drop[p.UserID] {
+				kept = append(kept, p)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range due {
+		if err := PurgeUser(db, id); err != nil {
+			return err
+		}
+		log.Printf("purged account %s", id)
+	}
+	return nil
+}
+
+// RunAccountPurger calls PurgeDueAccounts every interval. Run it in a goroutine.
+func RunAccountPurger(db *sql.DB, plans *utils.JSONStore[models.TripPlan], interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := PurgeDueAccounts(db, plans); err != nil {
+			log.Printf("account purge failed: %v", err)
+		}
+		<-t.C
+	}
+}
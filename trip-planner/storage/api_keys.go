@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix marks personal API keys so RequireAuth can tell them from JWTs.
+const APIKeyPrefix = "tp_"
+
+// API key scopes
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// APIKey is a personal key for scripts/CLI. Only its hash is stored.
+type APIKey struct {
+	ID         string   `json:"id"`
+	UserID     string   `json:"-"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"` // first chars of the key, to recognise it in a list
+	Scopes     []string `json:"scopes"`
+	CreatedAt  int64    `json:"created_at"`
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+	RevokedAt  int64    `json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func HashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func InsertAPIKey(db *sql.DB, k APIKey, raw string) error {
+	_, err := db.Exec(`INSERT INTO api_keys (id,user_id,name,prefix,key_hash,scopes,created_at) VALUES (?,?,?,?,?,?,?)`,
+		k.ID, k.UserID, k.Name, k.Prefix, HashAPIKey(raw), strings.Join(k.Scopes, ","), k.CreatedAt)
+	return err
+}
+
+// FindAPIKey looks up an unrevoked key by its raw value.
+func FindAPIKey(db *sql.DB, raw string) (APIKey, error) {
+	row := db.QueryRow(`SELECT id,user_id,name,prefix,scopes,created_at,COALESCE(last_used_at,0),0
+		FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL`, HashAPIKey(raw))
+	return scanAPIKey(row)
+}
+
+func ListAPIKeys(db *sql.DB, userID string) ([]APIKey, error) {
+	rows, err := db.Query(`SELECT id,user_id,name,prefix,scopes,created_at,COALESCE(last_used_at,0),COALESCE(revoked_at,0)
+		FROM api_keys WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func TouchAPIKey(db *sql.DB, id string) error {
+	_, err := db.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, time.Now().Unix(), id)
+	return err
+}
+
+func RevokeAPIKey(db *sql.DB, id, userID string) error {
+	return execOne(db, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().Unix(), id, userID)
+}
+
+func scanAPIKey(sc interface{ Scan(...any) error }) (APIKey, error) {
+	var k APIKey
+	var scopes string
+	err := sc.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	k.Scopes = strings.Split(scopes, ",")
+	return k, err
+}
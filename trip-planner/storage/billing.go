@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+func InsertCheckoutSession(db *sql.DB, id, userID, provider string) error {
+	_, err := db.Exec(`INSERT INTO checkout_sessions (id,user_id,provider,status,created_at) VALUES (?,?,?,?,?)`,
+		id, userID, provider, "open", time.Now().Unix())
+	return err
+}
+
+// CompleteCheckoutSession marks the session paid and returns its owner.
+func CompleteCheckoutSession(db *sql.DB, id string) (string, error) {
+	var uid string
+	if err := db.QueryRow(`SELECT user_id FROM checkout_sessions WHERE id = ?`, id).Scan(&uid); err != nil {
+		return "", err
+	}
+	_, err := db.Exec(`UPDATE checkout_sessions SET status = ?, completed_at = ? WHERE id = ?`,
+		"complete", time.Now().Unix(), id)
+	return uid, err
+}
+
+// RecordBillingEvent stores a webhook event id. It returns false when the
+// event was already processed (providers retry deliveries).
+func RecordBillingEvent(db *sql.DB, id, provider, typ string) (bool, error) {
+	res, err := db.Exec(`INSERT OR IGNORE INTO billing_events (id,provider,type,received_at) VALUES (?,?,?,?)`,
+		id, provider, typ, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ForgetBillingEvent removes an event whose handling failed, so the
+// provider's retry is processed instead of reported as a duplicate.
+func ForgetBillingEvent(db *sql.DB, id string) error {
+	_, err := db.Exec(`DELETE FROM billing_events WHERE id = ?`, id)
+	return err
+}
+
+func SetUserTier(db *sql.DB, userID, tier, customerID string) error {
+	if customerID == "" {
+		return execOne(db, `UPDATE users SET tier = ? WHERE id = ?`, tier, userID)
+	}
+	return execOne(db, `UPDATE users SET tier = ?, billing_customer_id = ? WHERE id = ?`, tier, customerID, userID)
+}
+
+func SetTierByCustomer(db *sql.DB, customerID, tier string) error {
+	return execOne(db, `UPDATE users SET tier = ? WHERE billing_customer_id = ?`, tier, customerID)
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Credit purchase statuses
+const (
+	PurchasePending = "pending"
+	PurchasePaid    = "paid"
+)
+
+// CreditPurchase is one bought pack; Remaining counts down as generations consume it.
+type CreditPurchase struct {
+	ID        string `json:"id"`
+	Pack      string `json:"pack"`
+	Credits   int    `json:"credits"`
+	Remaining int    `json:"remaining"`
+	Provider  string `json:"provider"`
+	Status    string `json:"status"`
+	CreatedAt int64  `json:"created_at"`
+	PaidAt    int64  `json:"paid_at,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+func InsertCreditPurchase(db *sql.DB, id, userID, pack string, credits int, provider string) error {
+	_, err := db.Exec(`INSERT INTO credit_purchases (id,user_id,pack,credits,remaining,provider,status,created_at)
+		VALUES (?,?,?,?,?,?,?,?)`,
+		id, userID, pack, credits, 0, provider, PurchasePending, time.Now().Unix())
+	return err
+}
+
+// MarkCreditPurchasePaid activates a pending purchase. It returns sql.ErrNoRows
+// when id is not a pending credit purchase.
+func MarkCreditPurchasePaid(db *sql.DB, id string, expiresAt time.Time) error {
+	return execOne(db, `UPDATE credit_purchases SET status = ?, remaining = credits, paid_at = ?, expires_at = ?
+		WHERE id = ? AND status = ?`,
+		PurchasePaid, time.Now().Unix(), expiresAt.Unix(), id, PurchasePending)
+}
+
+// IsCreditPurchase reports whether id is a credit purchase in any status.
+func IsCreditPurchase(db *sql.DB, id string) (bool, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM credit_purchases WHERE id = ?`, id).Scan(&n)
+	return n > 0, err
+}
+
+// CreditBalance sums unexpired paid credits.
+func CreditBalance(db *sql.DB, userID string) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COALESCE(SUM(remaining),0) FROM credit_purchases
+		WHERE user_id = ? AND status = ? AND expires_at > ?`,
+		userID, PurchasePaid, time.Now().Unix()).Scan(&n)
+	return n, err
+}
+
+// ConsumeCredit atomically takes one credit from the purchase expiring
+// soonest and returns that purchase's id, so it can be refunded. It returns
+// sql.ErrNoRows when the user has no usable credits.
+func ConsumeCredit(db *sql.DB, userID string) (string, error) {
+	var id string
+	err := db.QueryRow(`UPDATE credit_purchases SET remaining = remaining - 1
+		WHERE id = (
+			SELECT id FROM credit_purchases
+			WHERE user_id = ? AND status = ? AND remaining > 0 AND expires_at > ?
+			ORDER BY expires_at ASC LIMIT 1
+		) AND remaining > 0
+		RETURNING id`, userID, PurchasePaid, time.Now().Unix()).Scan(&id)
+	return id, err
+}
+
+// RefundCredit returns a credit taken by ConsumeCredit whose generation failed.
+func RefundCredit(db *sql.DB, purchaseID string) error {
+	return execOne(db, `UPDATE credit_purchases SET remaining = remaining + 1
+		WHERE id = ? AND remaining < credits`, purchaseID)
+}
+
+func ListCreditPurchases(db *sql.DB, userID string) ([]CreditPurchase, error) {
+	rows, err := db.Query(`SELECT id,pack,credits,remaining,provider,status,COALESCE(created_at,0),COALESCE(paid_at,0),COALESCE(expires_at,0)
+		FROM credit_purchases WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CreditPurchase{}
+	for rows.Next() {
+		var p CreditPurchase
+		if err := rows.Scan(&p.ID, &p.Pack, &p.Credits, &p.Remaining, &p.Provider, &p.Status,
+			&p.CreatedAt, &p.PaidAt, &p.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
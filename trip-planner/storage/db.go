@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -27,10 +28,152 @@ func Open(path string) (*sql.DB, error) {
 		generations INTEGER DEFAULT 0,
 		updated_at INTEGER
 	);
+
+	CREATE TABLE IF NOT EXISTS usage_ledger (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		plan_id TEXT,
+		model TEXT,
+		prompt_tokens INTEGER DEFAULT 0,
+		completion_tokens INTEGER DEFAULT 0,
+		latency_ms INTEGER DEFAULT 0,
+		cost_usd REAL DEFAULT 0,
+		outcome TEXT NOT NULL,
+		cache_hit INTEGER DEFAULT 0,
+		created_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_usage_ledger_user ON usage_ledger (user_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS checkout_sessions (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		status TEXT NOT NULL,
+		created_at INTEGER,
+		completed_at INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS credit_purchases (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		pack TEXT NOT NULL,
+		credits INTEGER NOT NULL,
+		remaining INTEGER NOT NULL DEFAULT 0,
+		provider TEXT NOT NULL,
+		status TEXT NOT NULL,
+		created_at INTEGER,
+		paid_at INTEGER,
+		expires_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_credit_purchases_user ON credit_purchases (user_id, status, expires_at);
+
+	CREATE TABLE IF NOT EXISTS user_identities (
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		email TEXT,
+		created_at INTEGER,
+		PRIMARY KEY (provider, subject)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id);
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		user_agent TEXT,
+		ip TEXT,
+		created_at INTEGER,
+		last_seen_at INTEGER,
+		expires_at INTEGER,
+		revoked_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);
+
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		token_hash TEXT PRIMARY KEY,
+		session_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at INTEGER,
+		expires_at INTEGER,
+		used_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens (session_id);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		created_at INTEGER,
+		last_used_at INTEGER,
+		revoked_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);
+
+	CREATE TABLE IF NOT EXISTS user_preferences (
+		user_id TEXT PRIMARY KEY,
+		budget TEXT,
+		pace TEXT,
+		interests TEXT,
+		dietary TEXT,
+		mobility TEXT,
+		with_kids INTEGER DEFAULT 0,
+		currency TEXT,
+		language TEXT,
+		updated_at INTEGER
+	);
+
+	CREATE TABLE IF NOT EXISTS login_tokens (
+		token_hash TEXT PRIMARY KEY,
+		email TEXT NOT NULL,
+		expires_at INTEGER NOT NULL,
+		used_at INTEGER,
+		created_at INTEGER
+	);
+	CREATE INDEX IF NOT EXISTS idx_login_tokens_email ON login_tokens (email, created_at);
+
+	CREATE TABLE IF NOT EXISTS billing_events (
+		id TEXT PRIMARY KEY,
+		provider TEXT NOT NULL,
+		type TEXT,
+		received_at INTEGER
+	);
 	`)
 	if err != nil {
 		return nil, err
 	}
 
+	// columns added after the first release (existing app.db files lack them)
+	if err := addColumns(db,
+		`ALTER TABLE users ADD COLUMN banned INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN banned_at INTEGER`,
+		`ALTER TABLE usage ADD COLUMN quota_override INTEGER`,
+		`ALTER TABLE users ADD COLUMN tier TEXT DEFAULT 'free'`,
+		`ALTER TABLE users ADD COLUMN billing_customer_id TEXT`,
+		`ALTER TABLE users ADD COLUMN deletion_requested_at INTEGER`,
+		`ALTER TABLE users ADD COLUMN delete_after INTEGER`,
+		`ALTER TABLE user_preferences ADD COLUMN language TEXT`,
+	); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
+
+// addColumns runs ALTER TABLE ... ADD COLUMN statements, ignoring columns that already exist.
+func addColumns(db *sql.DB, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Identity links an external login (provider + subject) to a users row.
+type Identity struct {
+	Provider  string `json:"provider"`
+	Subject   string `json:"-"`
+	Email     string `json:"email"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func FindIdentity(db *sql.DB, provider, subject string) (string, error) {
+	var uid string
+	err := db.QueryRow(`SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?`,
+		provider, subject).Scan(&uid)
+	return uid, err
+}
+
+func LinkIdentity(db *sql.DB, provider, subject, userID, email string) error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO user_identities (provider,subject,user_id,email,created_at) VALUES (?,?,?,?,?)`,
+		provider, subject, userID, email, time.Now().Unix())
+	return err
+}
+
+func ListIdentities(db *sql.DB, userID string) ([]Identity, error) {
+	rows, err := db.Query(`SELECT provider,subject,COALESCE(email,''),created_at FROM user_identities
+		WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Identity{}
+	for rows.Next() {
+		var i Identity
+		if err := rows.Scan(&i.Provider, &i.Subject, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	return out, rows.Err()
+}
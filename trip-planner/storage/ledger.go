@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Ledger outcomes
+const (
+	OutcomeSuccess       = "success"
+	OutcomeLimitReached  = "limit_reached"
+	OutcomePlacesFailed  = "places_failed"
+	OutcomeWeatherFailed = "weather_failed"
+	OutcomeAIFailed      = "ai_failed"
+	OutcomeSaveFailed    = "save_failed"
+	OutcomeTranslated    = "translated" // plan translation (not a generation)
+)
+
+// LedgerEntry is one generation attempt. The ledger is append-only.
+type LedgerEntry struct {
+	ID               int64   `json:"id"`
+	UserID           string  `json:"user_id"`
+	PlanID           string  `json:"plan_id"`
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	LatencyMs        int64   `json:"latency_ms"`
+	CostUSD          float64 `json:"cost_usd"`
+	Outcome          string  `json:"outcome"`
+	CacheHit         bool    `json:"cache_hit"`
+	CreatedAt        int64   `json:"created_at"`
+}
+
+type UsageSummary struct {
+	Attempts         int     `json:"attempts"`
+	Successes        int     `json:"successes"`
+	Failures         int     `json:"failures"`
+	Translations     int     `json:"translations"`
+	CacheHits        int     `json:"cache_hits"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+func InsertLedgerEntry(db *sql.DB, e LedgerEntry) error {
+	if e.CreatedAt == 0 {
+		e.CreatedAt = time.Now().Unix()
+	}
+	_, err := db.Exec(`INSERT INTO usage_ledger
+		(user_id,plan_id,model,prompt_tokens,completion_tokens,latency_ms,cost_usd,outcome,cache_hit,created_at)
+		VALUES (?,?,?,?,?,?,?,?,?,?)`,
+		e.UserID, e.PlanID, e.Model, e.PromptTokens, e.CompletionTokens,
+		e.LatencyMs, e.CostUSD, e.Outcome, e.CacheHit, e.CreatedAt)
+	return err
+}
+
+func SummarizeLedger(db *sql.DB, userID string) (UsageSummary, error) {
+	var s UsageSummary
+	err := db.QueryRow(`SELECT
+		COUNT(*),
+		COALESCE(SUM(CASE WHEN outcome = ? AND cache_hit = 0 THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN outcome NOT IN (?, ?) THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(cache_hit), 0),
+		COALESCE(SUM(prompt_tokens), 0),
+		COALESCE(SUM(completion_tokens), 0),
+		COALESCE(SUM(cost_usd), 0)
+		FROM usage_ledger WHERE user_id = ?`,
+		OutcomeSuccess, OutcomeSuccess, OutcomeTranslated, OutcomeTranslated, userID).
+		Scan(&s.Attempts, &s.Successes, &s.Failures, &s.Translations, &s.CacheHits,
+			&s.PromptTokens, &s.CompletionTokens, &s.CostUSD)
+	return s, err
+}
+
+// ListLedger returns the most recent entries for a user, newest first.
+func ListLedger(db *sql.DB, userID string, limit int) ([]LedgerEntry, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := db.Query(`SELECT id,user_id,COALESCE(plan_id,''),COALESCE(model,''),prompt_tokens,completion_tokens,
+		latency_ms,cost_usd,outcome,cache_hit,created_at
+		FROM usage_ledger WHERE user_id = ? ORDER BY id DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []LedgerEntry{}
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.PlanID, &e.Model, &e.PromptTokens, &e.CompletionTokens,
+			&e.LatencyMs, &e.CostUSD, &e.Outcome, &e.CacheHit, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
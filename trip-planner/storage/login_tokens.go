@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Magic-link tokens are stored hashed; the raw token only ever exists in the email.
+
+// InsertLoginToken stores a token unless one was issued for email within
+// cooldown; it returns false (and stores nothing) in that case. Check and
+// insert are one statement, so concurrent requests can't both get through.
+func InsertLoginToken(db *sql.DB, tokenHash, email string, expiresAt time.Time, cooldown time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := db.Exec(`INSERT INTO login_tokens (token_hash,email,expires_at,created_at)
+		SELECT ?,?,?,? WHERE NOT EXISTS (
+			SELECT 1 FROM login_tokens WHERE email = ? AND created_at > ?
+		)`,
+		tokenHash, email, expiresAt.Unix(), now.Unix(), email, now.Add(-cooldown).Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ConsumeLoginToken marks a token used and returns its email. Expired, used or
+// unknown tokens return sql.ErrNoRows.
+func ConsumeLoginToken(db *sql.DB, tokenHash string) (string, error) {
+	now := time.Now().Unix()
+	if err := execOne(db, `UPDATE login_tokens SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`, now, tokenHash, now); err != nil {
+		return "", err
+	}
+	var email string
+	err := db.QueryRow(`SELECT email FROM login_tokens WHERE token_hash = ?`, tokenHash).Scan(&email)
+	return email, err
+}
+
+func GetUserIDByEmail(db *sql.DB, email string) (string, error) {
+	var id string
+	err := db.QueryRow(`SELECT id FROM users WHERE lower(email) = ?`, strings.ToLower(email)).Scan(&id)
+	return id, err
+}
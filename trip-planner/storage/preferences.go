@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"trip-planner/models"
+)
+
+// GetPreferences returns the user's saved preferences (zero value if none).
+func GetPreferences(db *sql.DB, userID string) (models.UserPreferences, error) {
+	var p models.UserPreferences
+	var interests, dietary string
+	err := db.QueryRow(`SELECT COALESCE(budget,''),COALESCE(pace,''),COALESCE(interests,'[]'),COALESCE(dietary,'[]'),
+		COALESCE(mobility,''),COALESCE(with_kids,0),COALESCE(currency,''),COALESCE(language,'')
+		FROM user_preferences WHERE user_id = ?`, userID).
+		Scan(&p.Budget, &p.Pace, &interests, &dietary, &p.Mobility, &p.WithKids, &p.Currency, &p.Language)
+	if err == sql.ErrNoRows {
+		return models.UserPreferences{Interests: []string{}, Dietary: []string{}}, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	_ = json.Unmarshal([]byte(interests), &p.Interests)
+	_ = json.Unmarshal([]byte(dietary), &p.Dietary)
+	return p, nil
+}
+
+func SavePreferences(db *sql.DB, userID string, p models.UserPreferences) error {
+	if p.Interests == nil {
+		p.Interests = []string{}
+	}
+	if p.Dietary == nil {
+		p.Dietary = []string{}
+	}
+	interests, _ := json.Marshal(p.Interests)
+	dietary, _ := json.Marshal(p.Dietary)
+
+	_, err := db.Exec(`INSERT INTO user_preferences (user_id,budget,pace,interests,dietary,mobility,with_kids,currency,language,updated_at)
+		VALUES (?,?,?,?,?,?,?,?,?,?)
+		ON CONFLICT(user_id) DO UPDATE SET budget=excluded.budget, pace=excluded.pace, interests=excluded.interests,
+			dietary=excluded.dietary, mobility=excluded.mobility, with_kids=excluded.with_kids,
+			currency=excluded.currency, language=excluded.language, updated_at=excluded.updated_at`,
+		userID, p.Budget, p.Pace, string(interests), string(dietary), p.Mobility, p.WithKids, p.Currency, p.Language, time.Now().Unix())
+	return err
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshToken is one link in a rotation chain. All tokens of a session form
+// a family; presenting a used token again after the reuse grace window
+// revokes the whole family.
+type RefreshToken struct {
+	TokenHash string
+	SessionID string
+	UserID    string
+	ExpiresAt int64
+	UsedAt    int64
+}
+
+func InsertRefreshToken(db *sql.DB, tokenHash, sessionID, userID string, expiresAt time.Time) error {
+	_, err := db.Exec(`INSERT INTO refresh_tokens (token_hash,session_id,user_id,created_at,expires_at) VALUES (?,?,?,?,?)`,
+		tokenHash, sessionID, userID, time.Now().Unix(), expiresAt.Unix())
+	return err
+}
+
+func GetRefreshToken(db *sql.DB, tokenHash string) (RefreshToken, error) {
+	var t RefreshToken
+	err := db.QueryRow(`SELECT token_hash,session_id,user_id,expires_at,COALESCE(used_at,0)
+		FROM refresh_tokens WHERE token_hash = ?`, tokenHash).
+		Scan(&t.TokenHash, &t.SessionID, &t.UserID, &t.ExpiresAt, &t.UsedAt)
+	return t, err
+}
+
+// MarkRefreshTokenUsed flips used_at once. sql.ErrNoRows means another request
+// already used it.
+func MarkRefreshTokenUsed(db *sql.DB, tokenHash string) error {
+	return execOne(db, `UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL`,
+		time.Now().Unix(), tokenHash)
+}
+
+// RevokeSessionFamily revokes a session regardless of owner check and burns its refresh tokens.
+func RevokeSessionFamily(db *sql.DB, sessionID string) error {
+	now := time.Now().Unix()
+	if _, err := db.Exec(`UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, now, sessionID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`UPDATE refresh_tokens SET used_at = COALESCE(used_at, ?) WHERE session_id = ?`, now, sessionID)
+	return err
+}
+
+// ExtendSession slides the session expiry on refresh.
+func ExtendSession(db *sql.DB, sessionID string, expiresAt time.Time) error {
+	_, err := db.Exec(`UPDATE sessions SET expires_at = ?, last_seen_at = ? WHERE id = ?`,
+		expiresAt.Unix(), time.Now().Unix(), sessionID)
+	return err
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Session is a server-side login referenced by the JWT jti claim.
+type Session struct {
+	ID         string `json:"id"`
+	UserID     string `json:"-"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  int64  `json:"created_at"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	RevokedAt  int64  `json:"revoked_at,omitempty"`
+}
+
+func InsertSession(db *sql.DB, s Session) error {
+	_, err := db.Exec(`INSERT INTO sessions (id,user_id,user_agent,ip,created_at,last_seen_at,expires_at)
+		VALUES (?,?,?,?,?,?,?)`,
+		s.ID, s.UserID, s.UserAgent, s.IP, s.CreatedAt, s.LastSeenAt, s.ExpiresAt)
+	return err
+}
+
+// GetActiveSession returns an unrevoked, unexpired session owned by userID.
+func GetActiveSession(db *sql.DB, id, userID string) (Session, error) {
+	var s Session
+	err := db.QueryRow(`SELECT id,user_id,COALESCE(user_agent,''),COALESCE(ip,''),created_at,last_seen_at,expires_at
+		FROM sessions WHERE id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?`,
+		id, userID, time.Now().Unix()).
+		Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+	return s, err
+}
+
+func TouchSession(db *sql.DB, id, ip string) error {
+	_, err := db.Exec(`UPDATE sessions SET last_seen_at = ?, ip = ? WHERE id = ?`, time.Now().Unix(), ip, id)
+	return err
+}
+
+func ListActiveSessions(db *sql.DB, userID string) ([]Session, error) {
+	rows, err := db.Query(`SELECT id,user_id,COALESCE(user_agent,''),COALESCE(ip,''),created_at,last_seen_at,expires_at
+		FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC`, userID, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// RevokeSession revokes one of the user's sessions (sql.ErrNoRows if not found/already revoked).
+func RevokeSession(db *sql.DB, id, userID string) error {
+	return execOne(db, `UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().Unix(), id, userID)
+}
+
+// RevokeUserSessions revokes every session of a user except keepID (may be empty).
+func RevokeUserSessions(db *sql.DB, userID, keepID string) (int64, error) {
+	res, err := db.Exec(`UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id <> ? AND revoked_at IS NULL`,
+		time.Now().Unix(), userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
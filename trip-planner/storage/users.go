@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+type User struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	CreatedAt     int64  `json:"created_at"`
+	Banned        bool   `json:"banned"`
+	BannedAt      int64  `json:"banned_at,omitempty"`
+	Tier          string `json:"tier"`
+	DeleteAfter   int64  `json:"delete_after,omitempty"`
+	Generations   int    `json:"generations"`
+	QuotaOverride *int   `json:"quota_override"`
+}
+
+// Account tiers
+const (
+	TierFree = "free"
+	TierPro  = "pro"
+)
+
+const userColumns = `u.id, COALESCE(u.email,''), COALESCE(u.name,''), COALESCE(u.picture,''), COALESCE(u.created_at,0),
+	COALESCE(u.banned,0), COALESCE(u.banned_at,0), COALESCE(u.tier,'free'), COALESCE(u.delete_after,0), COALESCE(g.generations,0), g.quota_override`
+
+func scanUser(sc interface{ Scan(...any) error }) (User, error) {
+	var u User
+	var override sql.NullInt64
+	err := sc.Scan(&u.ID, &u.Email, &u.Name, &u.Picture, &u.CreatedAt,
+		&u.Banned, &u.BannedAt, &u.Tier, &u.DeleteAfter, &u.Generations, &override)
+	if override.Valid {
+		v := int(override.Int64)
+		u.QuotaOverride = &v
+	}
+	return u, err
+}
+
+func GetUser(db *sql.DB, id string) (User, error) {
+	row := db.QueryRow(`SELECT `+userColumns+`
+		FROM users u LEFT JOIN usage g ON g.user_id = u.id
+		WHERE u.id = ?`, id)
+	return scanUser(row)
+}
+
+// SearchUsers matches q against email and name. Empty q lists everyone.
+func SearchUsers(db *sql.DB, q string, limit, offset int) ([]User, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	like := "%" + q + "%"
+	rows, err := db.Query(`SELECT `+userColumns+`
+		FROM users u LEFT JOIN usage g ON g.user_id = u.id
+		WHERE ? = '' OR u.email LIKE ? OR u.name LIKE ?
+		ORDER BY u.created_at DESC LIMIT ? OFFSET ?`, q, like, like, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+func GetUserEmail(db *sql.DB, id string) (string, error) {
+	var email string
+	err := db.QueryRow(`SELECT COALESCE(email,'') FROM users WHERE id = ?`, id).Scan(&email)
+	return email, err
+}
+
+func IsBanned(db *sql.DB, id string) (bool, error) {
+	var banned bool
+	err := db.QueryRow(`SELECT COALESCE(banned,0) FROM users WHERE id = ?`, id).Scan(&banned)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return banned, err
+}
+
+func SetBanned(db *sql.DB, id string, banned bool) error {
+	var at any
+	if banned {
+		at = time.Now().Unix()
+	}
+	return execOne(db, `UPDATE users SET banned = ?, banned_at = ? WHERE id = ?`, banned, at, id)
+}
+
+// Usage is what quota checks need: the counter, the admin override (nil = tier default) and the tier.
+type Usage struct {
+	Generations   int
+	QuotaOverride *int
+	Tier          string
+}
+
+func GetUsage(db *sql.DB, id string) (Usage, error) {
+	var u Usage
+	var override sql.NullInt64
+	err := db.QueryRow(`SELECT g.generations, g.quota_override, COALESCE(u.tier,'free')
+		FROM usage g LEFT JOIN users u ON u.id = g.user_id
+		WHERE g.user_id = ?`, id).Scan(&u.Generations, &override, &u.Tier)
+	if err != nil {
+		return Usage{Tier: TierFree}, err
+	}
+	if override.Valid {
+		v := int(override.Int64)
+		u.QuotaOverride = &v
+	}
+	return u, nil
+}
+
+// SetQuotaOverride sets the per-user generation limit. nil restores the default.
+func SetQuotaOverride(db *sql.DB, id string, limit *int) error {
+	now := time.Now().Unix()
+	_, _ = db.Exec(`INSERT OR IGNORE INTO usage (user_id, generations, updated_at) VALUES (?, ?, ?)`, id, 0, now)
+	var v any
+	if limit != nil {
+		v = *limit
+	}
+	return execOne(db, `UPDATE usage SET quota_override = ?, updated_at = ? WHERE user_id = ?`, v, now, id)
+}
+
+func ResetUsage(db *sql.DB, id string) error {
+	now := time.Now().Unix()
+	_, err := db.Exec(`UPDATE usage SET generations = 0, updated_at = ? WHERE user_id = ?`, now, id)
+	return err
+}
+
+// execOne runs an UPDATE and reports sql.ErrNoRows when nothing matched.
+func execOne(db *sql.DB, query string, args ...any) error {
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
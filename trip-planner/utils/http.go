@@ -8,6 +8,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	neturl "net/url"
+	"strings"
 	"time"
 )
 
@@ -83,3 +85,29 @@ func PostJSON(ctx context.Context, url string, payload any, out any, headers map
 	dec := json.NewDecoder(resp.Body)
 	return dec.Decode(out)
 }
+
+// PostForm sends an application/x-www-form-urlencoded body and decodes a JSON response
+// (Stripe-style APIs).
+func PostForm(ctx context.Context, url string, form neturl.Values, out any, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("POST %s failed: %d - %s", url, resp.StatusCode, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
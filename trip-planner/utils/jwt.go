@@ -1,24 +1,40 @@
 package utils
 
 import (
+	"errors"
 	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func SignUserJWT(secret, userID string) (string, error) {
+// ErrTokenExpired lets RequireAuth tell clients to call /auth/refresh.
+var ErrTokenExpired = errors.New("access token expired")
+
+// SignUserJWT issues a short-lived access token. sessionID is the jti and
+// must match a row in the sessions table.
+func SignUserJWT(keys *Keyring, userID, sessionID string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"uid": userID,
-		"exp": time.Now().Add(30 * 24 * time.Hour).Unix(),
+		"jti": sessionID,
+		"exp": time.Now().Add(ttl).Unix(),
 	}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+	return keys.sign(claims)
 }
 
-func ParseUserJWT(secret, token string) (string, error) {
-	t, err := jwt.Parse(token, func(t *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
-	if err != nil || !t.Valid { return "", err }
+// ParseUserJWT returns the user id and session id (jti).
+func ParseUserJWT(keys *Keyring, token string) (string, string, error) {
+	t, err := jwt.Parse(token, keys.verifyKey, jwt.WithValidMethods(keys.algs()))
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return "", "", ErrTokenExpired
+	}
+	if err != nil || !t.Valid {
+		return "", "", err
+	}
 	claims := t.Claims.(jwt.MapClaims)
-	return claims["uid"].(string), nil
+	uid, _ := claims["uid"].(string)
+	sid, _ := claims["jti"].(string)
+	if uid == "" || sid == "" {
+		return "", "", errors.New("token missing uid or jti")
+	}
+	return uid, sid, nil
 }
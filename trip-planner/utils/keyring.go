@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms. Tokens declaring anything else are rejected.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is one JWT key. Verify-only keys (retired, or public-key-only)
+// have no signing material; NotAfter stops them verifying once set.
+type SigningKey struct {
+	ID       string
+	Alg      string
+	NotAfter time.Time
+
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// Keyring signs with the active key and verifies with any key still valid,
+// so secrets can be rotated without logging everyone out:
+// add the new key, make it active, drop the old one once its tokens expired.
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeyring(activeKID string, keys ...*SigningKey) (*Keyring, error) {
+	kr := &Keyring{keys: map[string]*SigningKey{}}
+	for _, k := range keys {
+		if _, dup := kr.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate jwt key id %q", k.ID)
+		}
+		kr.keys[k.ID] = k
+	}
+	active, ok := kr.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q not in keyring", activeKID)
+	}
+	if active.secret == nil && active.private == nil {
+		return nil, fmt.Errorf("active jwt key %q has no signing material", activeKID)
+	}
+	kr.active = active
+	return kr, nil
+}
+
+// NewHMACKey builds an HS256 key from a shared secret.
+func NewHMACKey(id, secret string) (*SigningKey, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("jwt key %q: empty HS256 secret", id)
+	}
+	return &SigningKey{ID: id, Alg: AlgHS256, secret: []byte(secret)}, nil
+}
+
+// LoadPEMKey reads an RS256 or EdDSA key from a PEM file. A PKCS#8 private key
+// can sign; a PKIX public key is verify-only.
+func LoadPEMKey(id, alg, path string) (*SigningKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", id, err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %q: no PEM block in %s", id, path)
+	}
+
+	k := &SigningKey{ID: id, Alg: alg}
+	if priv, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("jwt key %q: unsupported private key", id)
+		}
+		k.private = signer
+		k.public = signer.Public()
+	} else if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		k.public = pub
+	} else {
+		return nil, fmt.Errorf("jwt key %q: expected PKCS#8 private or PKIX public key", id)
+	}
+
+	switch alg {
+	case AlgRS256:
+		if _, ok := k.public.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt key %q: RS256 needs an RSA key", id)
+		}
+	case AlgEdDSA:
+		if _, ok := k.public.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt key %q: EdDSA needs an Ed25519 key", id)
+		}
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported alg %q", id, alg)
+	}
+	return k, nil
+}
+
+func (kr *Keyring) sign(claims jwt.MapClaims) (string, error) {
+	k := kr.active
+	t := jwt.NewWithClaims(signingMethod(k.Alg), claims)
+	t.Header["kid"] = k.ID
+	if k.secret != nil {
+		return t.SignedString(k.secret)
+	}
+	return t.SignedString(k.private)
+}
+
+// verifyKey is the jwt.Keyfunc: kid must name a known, unretired key and the
+// token's alg must be that key's alg (no alg confusion between HMAC and public keys).
+func (kr *Keyring) verifyKey(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := kr.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if !k.NotAfter.IsZero() && time.Now().After(k.NotAfter) {
+		return nil, fmt.Errorf("key %q retired", kid)
+	}
+	if t.Method.Alg() != k.Alg {
+		return nil, errors.New("token alg does not match key")
+	}
+	if k.secret != nil {
+		return k.secret, nil
+	}
+	return k.public, nil
+}
+
+func (kr *Keyring) algs() []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, k := range kr.keys {
+		if !seen[k.Alg] {
+			seen[k.Alg] = true
+			out = append(out, k.Alg)
+		}
+	}
+	return out
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
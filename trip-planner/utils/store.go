@@ -12,14 +12,52 @@ type JSONStore[T any] struct {
 	mu   sync.Mutex
 }
 
+var (
+	storesMu sync.Mutex
+	stores   = map[string]any{}
+)
+
+// NewJSONStore returns the store for path. Stores are shared per path so
+// every caller (controllers, background jobs) serialises on the same mutex.
 func NewJSONStore[T any](path string) *JSONStore[T] {
-	return &JSONStore[T]{Path: path}
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[path].(*JSONStore[T]); ok {
+		return s
+	}
+	s := &JSONStore[T]{Path: path}
+	stores[path] = s
+	return s
 }
 
 func (s *JSONStore[T]) ReadAll() ([]T, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.read()
+}
 
+func (s *JSONStore[T]) WriteAll(items []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(items)
+}
+
+// Update applies fn to the current items and writes the result, holding the
+// lock throughout so concurrent writers can't overwrite each other with a
+// stale snapshot. fn must not call back into the store.
+func (s *JSONStore[T]) Update(fn func([]T) []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.read()
+	if err != nil {
+		return err
+	}
+	return s.write(fn(items))
+}
+
+func (s *JSONStore[T]) read() ([]T, error) {
 	_ = os.MkdirAll(filepath.Dir(s.Path), 0755)
 
 	b, err := os.ReadFile(s.Path)
@@ -40,10 +78,7 @@ func (s *JSONStore[T]) ReadAll() ([]T, error) {
 	return items, nil
 }
 
-func (s *JSONStore[T]) WriteAll(items []T) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
+func (s *JSONStore[T]) write(items []T) error {
 	_ = os.MkdirAll(filepath.Dir(s.Path), 0755)
 
 	b, _ := json.MarshalIndent(items, "", "  ")